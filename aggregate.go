@@ -0,0 +1,213 @@
+package tiles
+
+// Densities returns the number of values under each populated tile at the zoom in a single pass over the keys.
+// Values added at a shallower zoom aren't under any single tile at the zoom, so they aren't counted.
+// A negative zoom is treated as 0, and a zoom past ZMax has no tiles
+func (idx *KeysetIndex) Densities(zoom int) map[Tile]int {
+	counts := make(map[Tile]int)
+	if zoom < 0 {
+		zoom = 0
+	}
+	if zoom > ZMax {
+		return counts
+	}
+	idx.rlockSorted()
+	defer idx.RUnlock()
+	var last pkey
+	var t Tile
+	for i, k := range idx.keys {
+		if k.qk.Level() < zoom {
+			continue
+		}
+		// sorted keys under the same tile are adjacent, so only convert when the prefix changes
+		if q := k.qk.Parent(zoom); i == 0 || q != last {
+			last, t = q, q.ToTile()
+		}
+		counts[t] += len(idx.values[k.v])
+	}
+	return counts
+}
+
+// EachPopulated calls fn with each tile at the zoom that has values under it and their count, in quadkey order, stopping early if fn returns false.
+// It's Densities streamed in a single pass without building a map, and likewise values added at a shallower zoom aren't counted.
+// Unlike Densities, a tile whose keys have no values is skipped rather than counted as 0. The zoom is clamped as in Densities.
+// fn is called with a readlock held, so it must not modify the index
+func (idx *KeysetIndex) EachPopulated(zoom int, fn func(t Tile, count int) bool) {
+	if zoom < 0 {
+		zoom = 0
+	}
+	if zoom > ZMax {
+		return
+	}
+	idx.rlockSorted()
+	defer idx.RUnlock()
+	var last pkey
+	n := 0
+	for _, k := range idx.keys {
+		vals := idx.values[k.v]
+		if k.qk.Level() < zoom || len(vals) == 0 {
+			continue
+		}
+		if q := k.qk.Parent(zoom); q != last || n == 0 {
+			if n > 0 && !fn(last.ToTile(), n) {
+				return
+			}
+			last, n = q, 0
+		}
+		n += len(vals)
+	}
+	if n > 0 {
+		fn(last.ToTile(), n)
+	}
+}
+
+// NormalizedDensities returns the Densities at the zoom divided by the largest of them, so the most populated tile is 1, along with that largest count.
+// An index without values at the zoom or deeper returns an empty map and 0. The zoom is clamped as in Densities
+func (idx *KeysetIndex) NormalizedDensities(zoom int) (map[Tile]float64, int) {
+	counts := idx.Densities(zoom)
+	max := 0
+	for _, c := range counts {
+		if c > max {
+			max = c
+		}
+	}
+	norm := make(map[Tile]float64, len(counts))
+	if max == 0 {
+		return norm, 0
+	}
+	for t, c := range counts {
+		norm[t] = float64(c) / float64(max)
+	}
+	return norm, max
+}
+
+// WeightedDensities returns the summed weight of the values under each populated tile at the zoom in a single pass over the keys.
+// Like Densities, values added at a shallower zoom aren't counted and the zoom is clamped. weight is called with a readlock held, so it must not modify the index
+func (idx *KeysetIndex) WeightedDensities(zoom int, weight func(val interface{}) float64) map[Tile]float64 {
+	sums := make(map[Tile]float64)
+	if zoom < 0 {
+		zoom = 0
+	}
+	if zoom > ZMax {
+		return sums
+	}
+	idx.rlockSorted()
+	defer idx.RUnlock()
+	var last pkey
+	var t Tile
+	for i, k := range idx.keys {
+		if k.qk.Level() < zoom {
+			continue
+		}
+		if q := k.qk.Parent(zoom); i == 0 || q != last {
+			last, t = q, q.ToTile()
+		}
+		for _, v := range idx.values[k.v] {
+			sums[t] += weight(v)
+		}
+	}
+	return sums
+}
+
+// Cluster is the values under a tile summarized as a single marker
+type Cluster struct {
+	Tile Tile
+	// Count is the number of values under the tile
+	Count int
+	// Lat and Lon are the mean of the values' coordinates
+	Lat, Lon float64
+}
+
+// Clusters returns a Cluster for each populated tile at the zoom in quadkey order, with the values positioned by coord, in a single pass over the keys.
+// Like Densities, values added at a shallower zoom aren't counted and the zoom is clamped. Coordinates are averaged as is, so a cluster straddling the antimeridian isn't centered on it.
+// coord is called with a readlock held, so it must not modify the index
+func (idx *KeysetIndex) Clusters(zoom int, coord func(val interface{}) (lat, lon float64)) (clusters []Cluster) {
+	if zoom < 0 {
+		zoom = 0
+	}
+	if zoom > ZMax {
+		return
+	}
+	idx.rlockSorted()
+	defer idx.RUnlock()
+	var last pkey
+	var c *Cluster
+	for _, k := range idx.keys {
+		vals := idx.values[k.v]
+		if k.qk.Level() < zoom || len(vals) == 0 {
+			continue
+		}
+		if q := k.qk.Parent(zoom); c == nil || q != last {
+			last = q
+			clusters = append(clusters, Cluster{Tile: q.ToTile()})
+			c = &clusters[len(clusters)-1]
+		}
+		for _, v := range vals {
+			lat, lon := coord(v)
+			c.Count++
+			// running mean so large clusters don't need a separate sum
+			c.Lat += (lat - c.Lat) / float64(c.Count)
+			c.Lon += (lon - c.Lon) / float64(c.Count)
+		}
+	}
+	return
+}
+
+// BuildPyramid returns the number of values under each populated tile at every zoom from minZoom to maxZoom, as Densities would for each zoom,
+// in a single pass over the keys. Sorted keys under a tile are adjacent, so a running count is kept per zoom and each tile is only written to the map once
+func (idx *KeysetIndex) BuildPyramid(minZoom, maxZoom int) map[Tile]int {
+	idx.rlockSorted()
+	defer idx.RUnlock()
+	counts := make(map[Tile]int)
+	if minZoom < 0 {
+		minZoom = 0
+	}
+	if maxZoom < minZoom {
+		return counts
+	}
+	tiles := make([]pkey, maxZoom-minZoom+1)
+	runs := make([]int, len(tiles))
+	flush := func(i int) {
+		if runs[i] > 0 {
+			counts[tiles[i].ToTile()] += runs[i]
+			runs[i] = 0
+		}
+	}
+	for _, k := range idx.keys {
+		n := len(idx.values[k.v])
+		for z := minZoom; z <= maxZoom && z <= k.qk.Level(); z++ {
+			i := z - minZoom
+			if q := k.qk.Parent(z); q != tiles[i] {
+				flush(i)
+				tiles[i] = q
+			}
+			runs[i] += n
+		}
+	}
+	for i := range tiles {
+		flush(i)
+	}
+	return counts
+}
+
+// Cardinality returns the number of distinct keys returned by key for the values aggregated under the tile, counting repeats of the same entity once.
+// It's exact, keeping each distinct key until the scan is done. key is called with a readlock held, so it must not modify the index
+func (idx *KeysetIndex) Cardinality(t Tile, key func(val interface{}) string) int {
+	seen := make(map[string]struct{})
+	idx.ForEach(t, func(val interface{}) bool {
+		seen[key(val)] = struct{}{}
+		return true
+	})
+	return len(seen)
+}
+
+// Reduce folds fn over the values aggregated under the tile, starting with init, without collecting them first.
+// Holds a readlock for the duration of the fold, so fn must not modify the index
+func (idx *KeysetIndex) Reduce(t Tile, init interface{}, fn func(acc, val interface{}) interface{}) interface{} {
+	acc := init
+	idx.ForEach(t, func(val interface{}) bool {
+		acc = fn(acc, val)
+		return true
+	})
+	return acc
+}
@@ -0,0 +1,277 @@
+package tiles
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestKeysetIndexDensities(t *testing.T) {
+	idx := &KeysetIndex{}
+	if d := idx.Densities(8); len(d) != 0 {
+		t.Error("Densities on an empty index: ", d)
+	}
+	idx.Add(FromCoordinate(40.7484, -73.9857, 18), "EmpireStateBuilding", "ChryslerBuilding")
+	idx.Add(FromCoordinate(40.6892, -74.0445, 18), "StatueOfLiberty")
+	idx.Add(FromCoordinate(51.5007, -0.1246, 18), "BigBen")
+	idx.Add(Tile{X: 1, Y: 1, Z: 2}, "Shallow")
+	tests := []struct {
+		zoom int
+		d    map[Tile]int
+	}{
+		{0, map[Tile]int{{}: 5}},
+		{2, map[Tile]int{{X: 1, Y: 1, Z: 2}: 5}},
+		{8, map[Tile]int{{X: 75, Y: 96, Z: 8}: 3, {X: 127, Y: 85, Z: 8}: 1}},
+		{19, map[Tile]int{}},
+		{-1, map[Tile]int{{}: 5}},
+		{ZMax + 1, map[Tile]int{}},
+	}
+	errf := "Densities(%d) -> %v"
+	for _, test := range tests {
+		if d := idx.Densities(test.zoom); !reflect.DeepEqual(d, test.d) {
+			t.Errorf(errf, test.zoom, d)
+		}
+	}
+}
+
+func TestKeysetIndexEachPopulated(t *testing.T) {
+	idx := &KeysetIndex{}
+	idx.Add(FromCoordinate(51.5007, -0.1246, 18), "BigBen")
+	idx.Add(FromCoordinate(40.7484, -73.9857, 18), "EmpireStateBuilding", "ChryslerBuilding")
+	idx.Add(FromCoordinate(40.6892, -74.0445, 18), "StatueOfLiberty")
+	idx.Add(Tile{X: 1, Y: 1, Z: 2}, "Shallow")
+	idx.Add(Tile{X: 3, Y: 3, Z: 2})
+	type count struct {
+		tile Tile
+		n    int
+	}
+	var counts []count
+	idx.EachPopulated(8, func(tile Tile, n int) bool {
+		counts = append(counts, count{tile, n})
+		return true
+	})
+	want := []count{{Tile{X: 127, Y: 85, Z: 8}, 1}, {Tile{X: 75, Y: 96, Z: 8}, 3}}
+	if !reflect.DeepEqual(counts, want) {
+		t.Error("EachPopulated(8) -> ", counts)
+	}
+	for _, zoom := range []int{0, 2, 18, -1, ZMax + 1} {
+		d := map[Tile]int{}
+		idx.EachPopulated(zoom, func(tile Tile, n int) bool {
+			d[tile] = n
+			return true
+		})
+		// unlike Densities, tiles whose keys have no values are skipped
+		want := idx.Densities(zoom)
+		for tile, n := range want {
+			if n == 0 {
+				delete(want, tile)
+			}
+		}
+		if !reflect.DeepEqual(d, want) {
+			t.Errorf("EachPopulated(%d) -> %v", zoom, d)
+		}
+	}
+	calls := 0
+	idx.EachPopulated(18, func(Tile, int) bool {
+		calls++
+		return false
+	})
+	if calls != 1 {
+		t.Error("EachPopulated didn't stop early: ", calls)
+	}
+	(&KeysetIndex{}).EachPopulated(0, func(tile Tile, n int) bool {
+		t.Error("EachPopulated on an empty index -> ", tile, n)
+		return true
+	})
+}
+
+func TestKeysetIndexNormalizedDensities(t *testing.T) {
+	idx := &KeysetIndex{}
+	if d, max := idx.NormalizedDensities(8); len(d) != 0 || max != 0 {
+		t.Error("NormalizedDensities on an empty index: ", d, max)
+	}
+	idx.Add(FromCoordinate(40.7484, -73.9857, 18), "EmpireStateBuilding", "ChryslerBuilding")
+	idx.Add(FromCoordinate(40.6892, -74.0445, 18), "StatueOfLiberty", "EllisIsland")
+	idx.Add(FromCoordinate(51.5007, -0.1246, 18), "BigBen")
+	tests := []struct {
+		zoom int
+		d    map[Tile]float64
+		max  int
+	}{
+		{0, map[Tile]float64{{}: 1}, 5},
+		{8, map[Tile]float64{{X: 75, Y: 96, Z: 8}: 1, {X: 127, Y: 85, Z: 8}: 0.25}, 4},
+		{18, map[Tile]float64{{X: 77197, Y: 98526, Z: 18}: 1, {X: 77154, Y: 98583, Z: 18}: 1, {X: 130981, Y: 87177, Z: 18}: 0.5}, 2},
+		{19, map[Tile]float64{}, 0},
+		{-1, map[Tile]float64{{}: 1}, 5},
+		{ZMax + 1, map[Tile]float64{}, 0},
+	}
+	errf := "NormalizedDensities(%d) -> %v, %d"
+	for _, test := range tests {
+		if d, max := idx.NormalizedDensities(test.zoom); !reflect.DeepEqual(d, test.d) || max != test.max {
+			t.Errorf(errf, test.zoom, d, max)
+		}
+	}
+}
+
+func TestKeysetIndexWeightedDensities(t *testing.T) {
+	idx := &KeysetIndex{}
+	height := func(val interface{}) float64 {
+		return float64(val.(landmark).Height)
+	}
+	if d := idx.WeightedDensities(8, height); len(d) != 0 {
+		t.Error("WeightedDensities on an empty index: ", d)
+	}
+	idx.Add(FromCoordinate(40.7484, -73.9857, 18), landmark{"EmpireStateBuilding", 443}, landmark{"ChryslerBuilding", 319})
+	idx.Add(FromCoordinate(40.6892, -74.0445, 18), landmark{"StatueOfLiberty", 93})
+	idx.Add(FromCoordinate(51.5007, -0.1246, 18), landmark{"BigBen", 96})
+	idx.Add(Tile{X: 1, Y: 1, Z: 2}, landmark{"Shallow", 1})
+	tests := []struct {
+		zoom int
+		d    map[Tile]float64
+	}{
+		{0, map[Tile]float64{{}: 952}},
+		{2, map[Tile]float64{{X: 1, Y: 1, Z: 2}: 952}},
+		{8, map[Tile]float64{{X: 75, Y: 96, Z: 8}: 855, {X: 127, Y: 85, Z: 8}: 96}},
+		{19, map[Tile]float64{}},
+		{-1, map[Tile]float64{{}: 952}},
+		{ZMax + 1, map[Tile]float64{}},
+	}
+	errf := "WeightedDensities(%d) -> %v"
+	for _, test := range tests {
+		if d := idx.WeightedDensities(test.zoom, height); !reflect.DeepEqual(d, test.d) {
+			t.Errorf(errf, test.zoom, d)
+		}
+	}
+}
+
+func TestKeysetIndexClusters(t *testing.T) {
+	type point struct{ lat, lon float64 }
+	idx := &KeysetIndex{}
+	coord := func(val interface{}) (float64, float64) {
+		p := val.(point)
+		return p.lat, p.lon
+	}
+	if c := idx.Clusters(8, coord); len(c) != 0 {
+		t.Error("Clusters on an empty index: ", c)
+	}
+	for _, p := range []point{{40.7484, -73.9857}, {40.6892, -74.0445}, {40.7128, -74.0060}, {51.5007, -0.1246}} {
+		idx.Add(FromCoordinate(p.lat, p.lon, 18), p)
+	}
+	idx.Add(Tile{X: 1, Y: 1, Z: 2}, point{0, 0})
+	tests := []struct {
+		zoom     int
+		clusters []Cluster
+	}{
+		{0, []Cluster{{Tile{}, 5, (40.7484 + 40.6892 + 40.7128 + 51.5007) / 5, (-73.9857 - 74.0445 - 74.0060 - 0.1246) / 5}}},
+		{8, []Cluster{
+			{Tile{X: 127, Y: 85, Z: 8}, 1, 51.5007, -0.1246},
+			{Tile{X: 75, Y: 96, Z: 8}, 3, (40.7484 + 40.6892 + 40.7128) / 3, (-73.9857 - 74.0445 - 74.0060) / 3},
+		}},
+		{19, nil},
+		{-1, []Cluster{{Tile{}, 5, (40.7484 + 40.6892 + 40.7128 + 51.5007) / 5, (-73.9857 - 74.0445 - 74.0060 - 0.1246) / 5}}},
+		{ZMax + 1, nil},
+	}
+	errf := "Clusters(%d) -> %v"
+	for _, test := range tests {
+		clusters := idx.Clusters(test.zoom, coord)
+		if len(clusters) != len(test.clusters) {
+			t.Errorf(errf, test.zoom, clusters)
+			continue
+		}
+		for i, c := range clusters {
+			exp := test.clusters[i]
+			if c.Tile != exp.Tile || c.Count != exp.Count || !floatEquals(c.Lat, exp.Lat) || !floatEquals(c.Lon, exp.Lon) {
+				t.Errorf(errf, test.zoom, clusters)
+			}
+		}
+	}
+}
+
+func TestKeysetIndexBuildPyramid(t *testing.T) {
+	idx := &KeysetIndex{}
+	hydrateIndexN(idx, 1000)
+	idx.Add(FromCoordinate(51.5007, -0.1246, 18), "BigBen")
+	idx.Add(Tile{X: 1, Y: 1, Z: 2}, "Shallow")
+	idx.Add(Tile{X: 75, Y: 96, Z: 8}, "NewYork")
+	tests := []struct {
+		minZoom, maxZoom int
+	}{
+		{0, 18},
+		{2, 8},
+		{8, 8},
+		{10, 20},
+	}
+	errf := "BuildPyramid(%d, %d) -> %d tiles"
+	for _, test := range tests {
+		p := idx.BuildPyramid(test.minZoom, test.maxZoom)
+		exp := make(map[Tile]int)
+		for z := test.minZoom; z <= test.maxZoom; z++ {
+			for tile, n := range idx.Densities(z) {
+				exp[tile] = n
+			}
+		}
+		if !reflect.DeepEqual(p, exp) {
+			t.Errorf(errf, test.minZoom, test.maxZoom, len(p))
+		}
+	}
+	if p := idx.BuildPyramid(0, 0); !reflect.DeepEqual(p, map[Tile]int{{}: 1003}) {
+		t.Errorf("BuildPyramid(0, 0) -> %v", p)
+	}
+	if p := idx.BuildPyramid(8, 2); len(p) != 0 {
+		t.Errorf("BuildPyramid(8, 2) -> %v", p)
+	}
+}
+
+func TestKeysetIndexCardinality(t *testing.T) {
+	idx := &KeysetIndex{}
+	esb := FromCoordinate(40.7484, -73.9857, 18)
+	sol := FromCoordinate(40.6892, -74.0445, 18)
+	name := func(val interface{}) string {
+		return val.(landmark).Name
+	}
+	if n := idx.Cardinality(Tile{}, name); n != 0 {
+		t.Error("Cardinality on an empty index: ", n)
+	}
+	idx.Add(esb, landmark{"EmpireStateBuilding", 443}, landmark{"EmpireStateBuilding", 381})
+	idx.Add(sol, landmark{"StatueOfLiberty", 93}, landmark{"EmpireStateBuilding", 443})
+	idx.Add(FromCoordinate(51.5007, -0.1246, 18), landmark{"BigBen", 96})
+	tests := []struct {
+		tile Tile
+		n    int
+	}{
+		{esb, 1},
+		{sol, 2},
+		{Tile{X: 75, Y: 96, Z: 8}, 2},
+		{Tile{}, 3},
+		{Tile{X: 106, Y: 194, Z: 9}, 0},
+	}
+	errf := "Cardinality(%v) -> %d"
+	for _, test := range tests {
+		if n := idx.Cardinality(test.tile, name); n != test.n {
+			t.Errorf(errf, test.tile, n)
+		}
+	}
+}
+
+func TestKeysetIndexReduce(t *testing.T) {
+	idx := &KeysetIndex{}
+	idx.Add(FromCoordinate(40.7484, -73.9857, 18), landmark{"EmpireStateBuilding", 443})
+	idx.Add(FromCoordinate(40.6892, -74.0445, 18), landmark{"StatueOfLiberty", 93})
+	idx.Add(FromCoordinate(51.5007, -0.1246, 18), landmark{"BigBen", 96})
+	sum := func(acc, val interface{}) interface{} {
+		return acc.(int) + val.(landmark).Height
+	}
+	tests := []struct {
+		tile Tile
+		h    int
+	}{
+		{Tile{}, 632},
+		{Tile{X: 75, Y: 96, Z: 8}, 536},
+		{Tile{X: 106, Y: 194, Z: 9}, 0},
+	}
+	errf := "Reduce(%+v) -> %v"
+	for _, test := range tests {
+		if h := idx.Reduce(test.tile, 0, sum); h != test.h {
+			t.Errorf(errf, test.tile, h)
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package tiles
+
+import (
+	"context"
+	"sync"
+)
+
+// BoundedIndex is a KeysetIndex that holds at most a fixed number of values, evicting the oldest added first.
+// Add reports the values it evicts, so BoundedIndex doesn't implement TileIndex.
+// Evicted values are hidden at once and dropped from the keyset together once as many have been evicted as the index holds,
+// so eviction costs amortized O(1) rather than a Delete each.
+// BoundedIndex is thread safe
+type BoundedIndex struct {
+	idx KeysetIndex
+	max int
+	// order holds the stored values oldest first, the first of them added as number first
+	order []Entry
+	first int
+	// stale is the number of evicted values still in the keyset
+	stale int
+	mu    sync.RWMutex
+}
+
+// boundedValue is a value stored in a BoundedIndex's keyset along with the number of values added before it
+type boundedValue struct {
+	seq int
+	val interface{}
+}
+
+// NewBoundedIndex returns an empty BoundedIndex that holds at most maxValues values, at least one
+func NewBoundedIndex(maxValues int) *BoundedIndex {
+	if maxValues < 1 {
+		maxValues = 1
+	}
+	return &BoundedIndex{max: maxValues}
+}
+
+// TileRange returns a channel of all tiles in the index in the zoom range. See KeysetIndex.TileRange
+func (idx *BoundedIndex) TileRange(zmin, zmax int) <-chan Tile {
+	return idx.TileRangeContext(context.Background(), zmin, zmax)
+}
+
+// TileRangeContext returns a channel of all tiles in the index in the zoom range. See KeysetIndex.TileRangeContext
+func (idx *BoundedIndex) TileRangeContext(ctx context.Context, zmin, zmax int) <-chan Tile {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	// tiles left with only evicted values would otherwise still be sent
+	if idx.stale > 0 {
+		idx.compact()
+	}
+	return idx.idx.TileRangeContext(ctx, zmin, zmax)
+}
+
+// Values returns a list of values aggregated under the requested tile
+func (idx *BoundedIndex) Values(t Tile) (vals []interface{}) {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+	for _, v := range idx.idx.Values(t) {
+		if b := v.(boundedValue); b.seq >= idx.first {
+			vals = append(vals, b.val)
+		}
+	}
+	return
+}
+
+// Len returns the number of values stored in the index
+func (idx *BoundedIndex) Len() int {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+	return len(idx.order)
+}
+
+// Add adds values to the tile, then evicts the oldest values until the index is back within its bound.
+// Returns the evicted entries oldest first
+func (idx *BoundedIndex) Add(t Tile, val ...interface{}) (evicted []Entry) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	stored := make([]interface{}, len(val))
+	for i, v := range val {
+		stored[i] = boundedValue{seq: idx.first + len(idx.order), val: v}
+		idx.order = append(idx.order, Entry{Tile: t, Value: v})
+	}
+	idx.idx.Add(t, stored...)
+	if n := len(idx.order) - idx.max; n > 0 {
+		evicted = append(evicted, idx.order[:n]...)
+		idx.order = idx.order[n:]
+		idx.first += n
+		idx.stale += n
+		if idx.stale >= idx.max {
+			idx.compact()
+		}
+	}
+	return
+}
+
+// compact drops the evicted values from the keyset and copies order so the evicted entries before it can be collected
+// Caller must hold the write lock
+func (idx *BoundedIndex) compact() {
+	first := idx.first
+	idx.idx.Filter(func(v interface{}) bool {
+		return v.(boundedValue).seq >= first
+	})
+	idx.order = append([]Entry(nil), idx.order...)
+	idx.stale = 0
+}
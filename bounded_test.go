@@ -0,0 +1,69 @@
+package tiles
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestBoundedIndex(t *testing.T) {
+	idx := NewBoundedIndex(3)
+	esb := FromCoordinate(40.7484, -73.9857, 18)
+	sol := FromCoordinate(40.6892, -74.0445, 18)
+	bbn := FromCoordinate(51.5007, -0.1246, 18)
+	nyc := Tile{X: 75, Y: 96, Z: 8}
+	tests := []struct {
+		tile    Tile
+		vals    []interface{}
+		evicted []Entry
+		nyc     int
+	}{
+		{esb, []interface{}{"EmpireStateBuilding"}, nil, 1},
+		{sol, []interface{}{"StatueOfLiberty", "LibertyIsland"}, nil, 3},
+		{bbn, []interface{}{"BigBen"}, []Entry{{esb, "EmpireStateBuilding"}}, 2},
+		{esb, []interface{}{"EmpireStateBuilding", "ChryslerBuilding"}, []Entry{{sol, "StatueOfLiberty"}, {sol, "LibertyIsland"}}, 2},
+		{bbn, []interface{}{"A", "B", "C", "D"}, []Entry{{bbn, "BigBen"}, {esb, "EmpireStateBuilding"}, {esb, "ChryslerBuilding"}, {bbn, "A"}}, 0},
+	}
+	errf := "BoundedIndex.Add(%+v, %v) -> %v"
+	for _, test := range tests {
+		evicted := idx.Add(test.tile, test.vals...)
+		if !reflect.DeepEqual(evicted, test.evicted) {
+			t.Errorf(errf, test.tile, test.vals, evicted)
+		}
+		if n := len(idx.Values(nyc)); n != test.nyc {
+			t.Errorf("BoundedIndex NYC expected %d values, got %d", test.nyc, n)
+		}
+		if n := idx.Len(); n > 3 {
+			t.Error("BoundedIndex holds more than 3 values ", n)
+		}
+	}
+	if v := idx.Values(bbn); !reflect.DeepEqual(v, []interface{}{"B", "C", "D"}) {
+		t.Error("BoundedIndex BBN: ", v)
+	}
+	// evicted values are dropped from the keyset in batches, and are never seen in between
+	idx = NewBoundedIndex(10)
+	for i := 0; i < 1000; i++ {
+		tile := Tile{X: i % 4, Y: 0, Z: 2}
+		if evicted := idx.Add(tile, i); i >= 10 && (len(evicted) != 1 || evicted[0].Value != i-10) {
+			t.Fatalf("BoundedIndex.Add(%v, %d) -> %v", tile, i, evicted)
+		}
+		if n := idx.idx.Len(); n > 20 {
+			t.Fatal("BoundedIndex keyset holds evicted values ", n)
+		}
+	}
+	if v := idx.Values(Tile{X: 1, Y: 0, Z: 2}); !reflect.DeepEqual(v, []interface{}{993, 997}) {
+		t.Error("BoundedIndex after eviction: ", v)
+	}
+	if n := idx.Len(); n != 10 {
+		t.Error("BoundedIndex.Len after eviction: ", n)
+	}
+	idx = NewBoundedIndex(2)
+	idx.Add(Tile{X: 0, Y: 0, Z: 1}, "A")
+	idx.Add(Tile{X: 1, Y: 0, Z: 1}, "B", "C")
+	var tiles []Tile
+	for tile := range idx.TileRange(1, 1) {
+		tiles = append(tiles, tile)
+	}
+	if !reflect.DeepEqual(tiles, []Tile{{X: 1, Y: 0, Z: 1}}) {
+		t.Error("BoundedIndex.TileRange sent an evicted tile: ", tiles)
+	}
+}
@@ -0,0 +1,64 @@
+package tiles
+
+import "context"
+
+// Number is the constraint for the weights of a ColumnarIndex
+type Number interface {
+	~int | ~int8 | ~int16 | ~int32 | ~int64 |
+		~uint | ~uint8 | ~uint16 | ~uint32 | ~uint64 | ~uintptr |
+		~float32 | ~float64
+}
+
+// ColumnarIndex is a TileIndex whose entries are a category of type K and a weight of type W, for summing weights per category without boxing them in a struct.
+// It uses a KeysetIndex internally, so it's thread safe and shares its aggregation semantics.
+type ColumnarIndex[K comparable, W Number] struct {
+	idx KeysetIndex
+}
+
+// column is an entry stored in a ColumnarIndex's internal index
+type column[K comparable, W Number] struct {
+	category K
+	weight   W
+}
+
+// NewColumnarIndex returns an empty ColumnarIndex for categories of type K and weights of type W
+func NewColumnarIndex[K comparable, W Number]() *ColumnarIndex[K, W] {
+	return &ColumnarIndex[K, W]{}
+}
+
+// TileRange returns a channel of all tiles in the index in the zoom range. See KeysetIndex.TileRange
+func (idx *ColumnarIndex[K, W]) TileRange(zmin, zmax int) <-chan Tile {
+	return idx.idx.TileRange(zmin, zmax)
+}
+
+// TileRangeContext returns a channel of all tiles in the index in the zoom range. See KeysetIndex.TileRangeContext
+func (idx *ColumnarIndex[K, W]) TileRangeContext(ctx context.Context, zmin, zmax int) <-chan Tile {
+	return idx.idx.TileRangeContext(ctx, zmin, zmax)
+}
+
+// Add adds an entry with the category and weight to the tile
+func (idx *ColumnarIndex[K, W]) Add(t Tile, category K, weight W) {
+	idx.idx.Add(t, column[K, W]{category, weight})
+}
+
+// Delete removes the first entry stored at exactly t with the category and weight. See KeysetIndex.Delete
+func (idx *ColumnarIndex[K, W]) Delete(t Tile, category K, weight W) bool {
+	return idx.idx.Delete(t, column[K, W]{category, weight})
+}
+
+// Count returns the number of entries aggregated under the tile
+func (idx *ColumnarIndex[K, W]) Count(t Tile) int {
+	return idx.idx.Count(t)
+}
+
+// SumByCategory returns the summed weight of each category among the entries aggregated under the tile, in a single walk of the keys under it.
+// Categories without entries under the tile are left out, so an empty tile returns an empty map
+func (idx *ColumnarIndex[K, W]) SumByCategory(t Tile) map[K]W {
+	sums := make(map[K]W)
+	idx.idx.ForEach(t, func(val interface{}) bool {
+		c := val.(column[K, W])
+		sums[c.category] += c.weight
+		return true
+	})
+	return sums
+}
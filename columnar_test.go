@@ -0,0 +1,51 @@
+package tiles
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestColumnarIndex(t *testing.T) {
+	type category string
+	idx := NewColumnarIndex[category, float64]()
+	esb := FromCoordinate(40.7484, -73.9857, 18)
+	sol := FromCoordinate(40.6892, -74.0445, 18)
+	bbn := FromCoordinate(51.5007, -0.1246, 18)
+	idx.Add(esb, "office", 2.5)
+	idx.Add(esb, "retail", 1)
+	idx.Add(sol, "office", 0.5)
+	idx.Add(sol, "park", 4)
+	idx.Add(bbn, "office", 8)
+	nyc := Tile{X: 75, Y: 96, Z: 8}
+	tests := []struct {
+		tile Tile
+		sums map[category]float64
+	}{
+		{nyc, map[category]float64{"office": 3, "retail": 1, "park": 4}},
+		{esb, map[category]float64{"office": 2.5, "retail": 1}},
+		{Tile{}, map[category]float64{"office": 11, "retail": 1, "park": 4}},
+		{Tile{X: 106, Y: 194, Z: 9}, map[category]float64{}},
+	}
+	errf := "ColumnarIndex.SumByCategory(%v) -> %v"
+	for _, test := range tests {
+		if sums := idx.SumByCategory(test.tile); !reflect.DeepEqual(sums, test.sums) {
+			t.Errorf(errf, test.tile, sums)
+		}
+	}
+	if idx.Count(nyc) != 4 {
+		t.Error("ColumnarIndex.Count: ", idx.Count(nyc))
+	}
+	if !idx.Delete(sol, "park", 4) || idx.Delete(sol, "park", 4) {
+		t.Error("ColumnarIndex.Delete didn't remove the entry once")
+	}
+	if sums := idx.SumByCategory(sol); !reflect.DeepEqual(sums, map[category]float64{"office": 0.5}) {
+		t.Errorf(errf, sol, sums)
+	}
+	c := 0
+	for range idx.TileRange(18, 18) {
+		c++
+	}
+	if c != 3 {
+		t.Error("ColumnarIndex.TileRange tiles: ", c)
+	}
+}
@@ -19,16 +19,7 @@ func (c Coordinate) Equals(that Coordinate) bool {
 
 // ToPixel gets the Pixel of the coord at the zoom level
 func (c Coordinate) ToPixel(zoom int) Pixel {
-	x := (c.Lon + 180) / 360.0
-	sinLat := math.Sin(c.Lat * math.Pi / 180.0)
-	y := 0.5 - math.Log((1+sinLat)/(1-sinLat))/(4*math.Pi)
-	size := float64(mapDimensions(zoom))
-	return Pixel{
-		X: int(clip(x*size+0.5, 0, size-1)),
-		Y: int(clip(y*size+0.5, 0, size-1)),
-		Z: zoom,
-	}
-
+	return grid().CoordinateToPixel(c, zoom)
 }
 
 func (c Coordinate) String() string {
@@ -43,3 +34,23 @@ func ClippedCoords(lat, lon float64) Coordinate {
 		Lon: clip(lon, MinLon, MaxLon),
 	}
 }
+
+// DistanceMeters returns the great-circle distance between two WGS84 coordinates using the haversine formula.
+// The earth is treated as a sphere of radius EarthRadiusM
+func DistanceMeters(lat1, lon1, lat2, lon2 float64) float64 {
+	phi1, phi2 := lat1*math.Pi/180, lat2*math.Pi/180
+	dphi := phi2 - phi1
+	dlambda := (lon2 - lon1) * math.Pi / 180
+	a := math.Pow(math.Sin(dphi/2), 2) + math.Cos(phi1)*math.Cos(phi2)*math.Pow(math.Sin(dlambda/2), 2)
+	return 2 * EarthRadiusM * math.Asin(math.Min(1, math.Sqrt(a)))
+}
+
+// BearingDegrees returns the initial great-circle bearing from the first WGS84 coordinate to the second using the forward azimuth formula.
+// It's in degrees clockwise from north in the range [0, 360), and 0 if the coordinates are the same
+func BearingDegrees(lat1, lon1, lat2, lon2 float64) float64 {
+	phi1, phi2 := lat1*math.Pi/180, lat2*math.Pi/180
+	dlambda := (lon2 - lon1) * math.Pi / 180
+	y := math.Sin(dlambda) * math.Cos(phi2)
+	x := math.Cos(phi1)*math.Sin(phi2) - math.Sin(phi1)*math.Cos(phi2)*math.Cos(dlambda)
+	return math.Mod(math.Atan2(y, x)*180/math.Pi+360, 360)
+}
@@ -1,6 +1,7 @@
 package tiles
 
 import (
+	"math"
 	"testing"
 )
 
@@ -19,3 +20,47 @@ func TestToPixel(t *testing.T) {
 		}
 	}
 }
+
+func TestDistanceMeters(t *testing.T) {
+	distTests := []struct {
+		lat1, lon1, lat2, lon2 float64
+		m                      float64
+	}{
+		{40.7484, -73.9857, 40.7484, -73.9857, 0},
+		{0, 0, 0, 180, math.Pi * EarthRadiusM},
+		{0, 0, 0, -90, math.Pi * EarthRadiusM / 2},
+		{90, 0, -90, 0, math.Pi * EarthRadiusM},
+		{40.7484, -73.9857, 40.6892, -74.0445, 8248.72},
+		{40.7484, -73.9857, 51.5007, -0.1246, 5573090.63},
+	}
+	errf := "DistanceMeters(%v, %v, %v, %v) -> %v"
+	for _, test := range distTests {
+		m := DistanceMeters(test.lat1, test.lon1, test.lat2, test.lon2)
+		if math.Abs(m-test.m) > 1 {
+			t.Errorf(errf, test.lat1, test.lon1, test.lat2, test.lon2, m)
+		}
+	}
+}
+
+func TestBearingDegrees(t *testing.T) {
+	bearingTests := []struct {
+		lat1, lon1, lat2, lon2 float64
+		deg                    float64
+	}{
+		{40.7484, -73.9857, 40.7484, -73.9857, 0},
+		{0, 0, 10, 0, 0},
+		{0, 0, 0, 10, 90},
+		{0, 0, -10, 0, 180},
+		{0, 0, 0, -10, 270},
+		{0, 179, 0, -179, 90},
+		{40.7484, -73.9857, 51.5007, -0.1246, 51.25},
+		{51.5007, -0.1246, 40.7484, -73.9857, 288.36},
+	}
+	errf := "BearingDegrees(%v, %v, %v, %v) -> %v"
+	for _, test := range bearingTests {
+		deg := BearingDegrees(test.lat1, test.lon1, test.lat2, test.lon2)
+		if math.Abs(deg-test.deg) > 0.01 || deg < 0 || deg >= 360 {
+			t.Errorf(errf, test.lat1, test.lon1, test.lat2, test.lon2, deg)
+		}
+	}
+}
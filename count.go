@@ -0,0 +1,59 @@
+package tiles
+
+import "context"
+
+// CountIndex stores precomputed counts per tile, such as those aggregated by a batch job, and serves them summed up like a KeysetIndex serves values.
+// Count of a tile is the sum of the counts set at it and every tile under it.
+// It uses a KeysetIndex internally, so it's thread safe and shares its sorted keyset.
+type CountIndex struct {
+	idx KeysetIndex
+}
+
+// NewCountIndex returns an empty CountIndex
+func NewCountIndex() *CountIndex {
+	return &CountIndex{}
+}
+
+// SetCount sets the count stored at exactly t to n, replacing any count set there before.
+// A count of 0 or less removes the tile, so it isn't sent by TileRange
+func (c *CountIndex) SetCount(t Tile, n int) {
+	c.idx.Lock()
+	defer c.idx.Unlock()
+	// each tile's count is upserted under its quadkey, so setting it again replaces the count in place
+	id := string(t.Quadkey())
+	if n > 0 {
+		c.idx.upsert(t, id, n)
+	} else if k, ok := c.idx.ids[id]; ok {
+		c.idx.remove(c.idx.find(k))
+	}
+}
+
+// Count returns the sum of the counts set at the tile and every tile under it
+func (c *CountIndex) Count(t Tile) (n int) {
+	c.idx.ForEach(t, func(val interface{}) bool {
+		n += val.(int)
+		return true
+	})
+	return
+}
+
+// TileRange returns a channel of all tiles in the index in the zoom range. See KeysetIndex.TileRange
+func (c *CountIndex) TileRange(zmin, zmax int) <-chan Tile {
+	return c.idx.TileRange(zmin, zmax)
+}
+
+// TileRangeCounts is TileRange with the Count of each tile, in a single pass like KeysetIndex.TileRangeCounts
+func (c *CountIndex) TileRangeCounts(zmin, zmax int) <-chan TileCount {
+	return c.TileRangeCountsContext(context.Background(), zmin, zmax)
+}
+
+// TileRangeCountsContext is TileRangeCounts that stops sending and closes the channel when ctx is done.
+// Cancel ctx when abandoning the channel early so the readlock is released.
+func (c *CountIndex) TileRangeCountsContext(ctx context.Context, zmin, zmax int) <-chan TileCount {
+	return c.idx.tileRangeCounts(ctx, zmin, zmax, func(vals []interface{}) (n int) {
+		for _, v := range vals {
+			n += v.(int)
+		}
+		return
+	})
+}
@@ -0,0 +1,82 @@
+package tiles
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestCountIndex(t *testing.T) {
+	idx := NewCountIndex()
+	esb := FromCoordinate(40.7484, -73.9857, 18)
+	sol := FromCoordinate(40.6892, -74.0445, 18)
+	bbn := FromCoordinate(51.5007, -0.1246, 18)
+	nyc := Tile{X: 75, Y: 96, Z: 8}
+	idx.SetCount(esb, 10)
+	idx.SetCount(sol, 3)
+	idx.SetCount(bbn, 7)
+	idx.SetCount(nyc, 100)
+	idx.SetCount(esb, 20)
+	tests := []struct {
+		tile  Tile
+		count int
+	}{
+		{esb, 20},
+		{sol, 3},
+		{nyc, 123},
+		{Tile{}, 130},
+		{Tile{X: 106, Y: 194, Z: 9}, 0},
+	}
+	errf := "CountIndex.Count(%v) -> %d"
+	for _, test := range tests {
+		if n := idx.Count(test.tile); n != test.count {
+			t.Errorf(errf, test.tile, n)
+		}
+	}
+	idx.SetCount(sol, 0)
+	idx.SetCount(Tile{X: 1, Y: 1, Z: 1}, 0)
+	if n := idx.Count(nyc); n != 120 {
+		t.Errorf(errf, nyc, n)
+	}
+	tiles := 0
+	for c := range idx.TileRangeCounts(0, 18) {
+		tiles++
+		if n := idx.Count(c.Tile); c.Count != n {
+			t.Errorf("CountIndex.TileRangeCounts %v -> %d != Count %d", c.Tile, c.Count, n)
+		}
+	}
+	exp := 0
+	for range idx.TileRange(0, 18) {
+		exp++
+	}
+	if tiles != exp || tiles == 0 {
+		t.Errorf("CountIndex.TileRangeCounts sent %d tiles != TileRange %d", tiles, exp)
+	}
+	for tile := range idx.TileRange(18, 18) {
+		if tile == sol {
+			t.Error("CountIndex.SetCount(0) didn't remove the tile")
+		}
+	}
+}
+
+func TestCountIndexTileRangeCountsContext(t *testing.T) {
+	idx := NewCountIndex()
+	for i := 0; i < 1<<12; i++ {
+		idx.SetCount(Tile{X: i, Y: i, Z: 18}, i+1)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	for range idx.TileRangeCountsContext(ctx, 0, 18) {
+		break
+	}
+	cancel()
+	done := make(chan struct{})
+	go func() {
+		idx.SetCount(Tile{X: 1, Y: 1, Z: 18}, 1)
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Error("CountIndex.TileRangeCountsContext did not release the lock after cancel")
+	}
+}
@@ -0,0 +1,279 @@
+package tiles
+
+import (
+	"math"
+	"sort"
+)
+
+// TilesForBBox returns the tiles at zoom that intersect the bbox in row-major order, north to south then west to east.
+// If minLon > maxLon the bbox crosses the antimeridian and the x range wraps around it, so the tiles east of the antimeridian follow those west of it in each row
+func TilesForBBox(minLat, minLon, maxLat, maxLon float64, zoom int) (tiles []Tile) {
+	return grid().TilesForBBox(minLat, minLon, maxLat, maxLon, zoom)
+}
+
+// TilesForBBoxSpiral returns the tiles from TilesForBBox ordered by the great-circle distance of their centers from the bbox's center, nearest first,
+// so a viewport can load from the middle out. Tiles the same distance away keep their row-major order.
+// The center of a bbox crossing the antimeridian is taken across it
+func TilesForBBoxSpiral(minLat, minLon, maxLat, maxLon float64, zoom int) []Tile {
+	tiles := TilesForBBox(minLat, minLon, maxLat, maxLon, zoom)
+	if maxLon < minLon {
+		maxLon += 360
+	}
+	clat, clon := (minLat+maxLat)/2, wrapLon((minLon+maxLon)/2)
+	dists := make([]float64, len(tiles))
+	for i, t := range tiles {
+		lat, lon := t.Center()
+		dists[i] = DistanceMeters(clat, clon, lat, lon)
+	}
+	sort.Stable(byCenter{tiles, dists})
+	return tiles
+}
+
+type byCenter struct {
+	tiles []Tile
+	dists []float64
+}
+
+func (c byCenter) Len() int           { return len(c.tiles) }
+func (c byCenter) Less(i, j int) bool { return c.dists[i] < c.dists[j] }
+func (c byCenter) Swap(i, j int) {
+	c.tiles[i], c.tiles[j] = c.tiles[j], c.tiles[i]
+	c.dists[i], c.dists[j] = c.dists[j], c.dists[i]
+}
+
+// GridLines returns the edges of the tiles from TilesForBBox as {lat, lon} segments, each edge shared by two tiles only once.
+// The horizontal edges come first, running west to east in row-major order, followed by the vertical edges running north to south.
+// Where the bbox crosses the antimeridian the edges at -180 and 180 are both returned, since they're drawn at different ends of the map
+func GridLines(minLat, minLon, maxLat, maxLon float64, zoom int) (lines [][2][2]float64) {
+	tiles := TilesForBBox(minLat, minLon, maxLat, maxLon, zoom)
+	// edges are keyed by the tile whose top or left they are, so the bottom and right of a tile are the top and left of the ones after it
+	type edge struct {
+		x, y     int
+		vertical bool
+	}
+	seen := make(map[edge]struct{}, 2*len(tiles))
+	add := func(e edge) {
+		if _, ok := seen[e]; ok {
+			return
+		}
+		seen[e] = struct{}{}
+		t := Tile{X: e.x, Y: e.y, Z: zoom}
+		_, west, north, _ := t.Bounds()
+		if e.vertical {
+			south, _, _, _ := t.Bounds()
+			lines = append(lines, [2][2]float64{{north, west}, {south, west}})
+		} else {
+			_, _, _, east := t.Bounds()
+			lines = append(lines, [2][2]float64{{north, west}, {north, east}})
+		}
+	}
+	for _, t := range tiles {
+		add(edge{x: t.X, y: t.Y})
+		add(edge{x: t.X, y: t.Y + 1})
+	}
+	for _, t := range tiles {
+		add(edge{x: t.X, y: t.Y, vertical: true})
+		add(edge{x: t.X + 1, y: t.Y, vertical: true})
+	}
+	return
+}
+
+// QuadkeyCover returns the fewest quadkeys of mixed levels that cover the same tiles as TilesForBBox at maxZoom, in quadkey order.
+// Starting from those tiles, any four siblings are merged into their parent, level by level up to the root, so the cells don't overlap
+func QuadkeyCover(minLat, minLon, maxLat, maxLon float64, maxZoom int) []Quadkey {
+	level := make(map[Quadkey]struct{})
+	for _, t := range TilesForBBox(minLat, minLon, maxLat, maxLon, maxZoom) {
+		level[t.Quadkey()] = struct{}{}
+	}
+	var cover []Quadkey
+	for z := maxZoom; z > 0 && len(level) > 0; z-- {
+		parents := make(map[Quadkey]struct{})
+		for qk := range level {
+			p := qk.Parent(z - 1)
+			if _, ok := parents[p]; ok {
+				continue
+			}
+			full := true
+			for _, c := range p.Children() {
+				if _, ok := level[c]; !ok {
+					full = false
+					break
+				}
+			}
+			if full {
+				parents[p] = struct{}{}
+			} else {
+				cover = append(cover, qk)
+			}
+		}
+		level = parents
+	}
+	for qk := range level {
+		cover = append(cover, qk)
+	}
+	sort.Slice(cover, func(i, j int) bool { return cover[i] < cover[j] })
+	return cover
+}
+
+// TilesForPolygon returns the tiles at zoom whose centers are inside the polygon ring in row-major order.
+// Each point in ring is a {lat, lon} pair and the ring doesn't need to be closed.
+// The result is undefined for self-intersecting rings and rings that cross the antimeridian
+func TilesForPolygon(ring [][2]float64, zoom int) (tiles []Tile) {
+	if len(ring) < 3 {
+		return
+	}
+	minLat, minLon, maxLat, maxLon := ring[0][0], ring[0][1], ring[0][0], ring[0][1]
+	for _, p := range ring[1:] {
+		minLat, maxLat = math.Min(minLat, p[0]), math.Max(maxLat, p[0])
+		minLon, maxLon = math.Min(minLon, p[1]), math.Max(maxLon, p[1])
+	}
+	for _, t := range TilesForBBox(minLat, minLon, maxLat, maxLon, zoom) {
+		if lat, lon := t.Center(); inRing(ring, lat, lon) {
+			tiles = append(tiles, t)
+		}
+	}
+	return
+}
+
+// TilesForCircle returns the tiles at zoom that come within radiusMeters of the coordinate in row-major order.
+// The candidates are the tiles in the circle's bbox, which wraps the antimeridian if the circle crosses it.
+// A circle that reaches a pole or spans more than the whole longitude range uses every column in its latitude band
+func TilesForCircle(lat, lon, radiusMeters float64, zoom int) (tiles []Tile) {
+	dLat, dLon, ok := degreesForMeters(lat, radiusMeters)
+	minLat, maxLat := lat-dLat, lat+dLat
+	minLon, maxLon := MinLon, MaxLon
+	if ok {
+		minLon, maxLon = wrapLon(lon-dLon), wrapLon(lon+dLon)
+	}
+	minLat, maxLat = clip(minLat, MinLat, MaxLat), clip(maxLat, MinLat, MaxLat)
+	for _, t := range TilesForBBox(minLat, minLon, maxLat, maxLon, zoom) {
+		if distanceToBounds(lat, lon, t) <= radiusMeters {
+			tiles = append(tiles, t)
+		}
+	}
+	return
+}
+
+// distanceToBounds returns the distance in meters from the coordinate to the closest point within the tile's bounds.
+// It's 0 if the tile contains the coordinate
+func distanceToBounds(lat, lon float64, t Tile) float64 {
+	minLat, minLon, maxLat, maxLon := t.Bounds()
+	if w, e := wrapLon(minLon-lon), wrapLon(lon-maxLon); w > 0 || e > 0 {
+		// lon is outside the tile, so the closest point is on its west or east edge
+		return math.Min(distanceToMeridian(lat, lon, minLon, minLat, maxLat), distanceToMeridian(lat, lon, maxLon, minLat, maxLat))
+	}
+	return DistanceMeters(lat, lon, clip(lat, minLat, maxLat), lon)
+}
+
+// distanceToMeridian returns the distance in meters from the coordinate to the closest point on the meridian between minLat and maxLat.
+// The great circle through the coordinate meeting the meridian at a right angle does so at the closest point on it, usually poleward of lat,
+// and the distance grows going around the meridian from there, so the closest point on the edge is that foot or one of its ends
+func distanceToMeridian(lat, lon, meridian, minLat, maxLat float64) float64 {
+	d := math.Min(DistanceMeters(lat, lon, minLat, meridian), DistanceMeters(lat, lon, maxLat, meridian))
+	rad := math.Pi / 180
+	if foot := math.Atan2(math.Sin(lat*rad), math.Cos(lat*rad)*math.Cos((meridian-lon)*rad)) / rad; foot > minLat && foot < maxLat {
+		d = math.Min(d, DistanceMeters(lat, lon, foot, meridian))
+	}
+	return d
+}
+
+// TilesForLine returns the tiles at zoom that the polyline passes through in the order the line reaches them, each tile only once.
+// Each point is a {lat, lon} pair. Segments are walked cell by cell as straight lines on the mercator map, rhumb lines rather than great circles,
+// and a segment whose ends are more than 180 degrees of longitude apart is taken to cross the antimeridian and split there
+func TilesForLine(points [][2]float64, zoom int) (tiles []Tile) {
+	return grid().TilesForLine(points, zoom)
+}
+
+// tileSpace returns the coordinate's position on the mercator map measured in tiles, for a map n tiles wide
+func tileSpace(lat, lon, n float64) (x, y float64) {
+	c := ClippedCoords(lat, lon)
+	sinLat := math.Sin(c.Lat * math.Pi / 180)
+	x = (c.Lon + 180) / 360 * n
+	y = (0.5 - math.Log((1+sinLat)/(1-sinLat))/(4*math.Pi)) * n
+	return
+}
+
+// tileCell returns the column and row of the tile at zoom containing the coordinate by flooring its tileSpace position,
+// so a point on a tile's edge is in the tile east or south of it as in Tile.Contains
+func tileCell(lat, lon float64, zoom int) (x, y int) {
+	n := float64(uint(1) << uint(zoom))
+	fx, fy := tileSpace(lat, lon, n)
+	return int(clip(math.Floor(fx), 0, n-1)), int(clip(math.Floor(fy), 0, n-1))
+}
+
+// lastCell returns the cell on a line of n cells holding the end of the span from lo to hi, clipped to the line.
+// An end exactly on a cell's edge past lo only touches the next cell, so it's in the cell before the edge
+func lastCell(lo, hi, n float64) int {
+	c := math.Floor(hi)
+	if c == hi && hi > lo {
+		c--
+	}
+	return int(clip(c, 0, n-1))
+}
+
+// walkSegment calls visit for each tile the segment passes through from the start to the end, in tile space on a map n tiles wide.
+// It steps one row or column at a time toward the end tile, so it always takes exactly as many steps as the tiles are apart
+func walkSegment(x0, y0, x1, y1, n float64, visit func(x, y int)) {
+	cell := func(v float64) int {
+		return int(clip(math.Floor(v), 0, n-1))
+	}
+	cx, cy, ex, ey := cell(x0), cell(y0), cell(x1), cell(y1)
+	stepX, nextX, deltaX := step(x0, x1, cx)
+	stepY, nextY, deltaY := step(y0, y1, cy)
+	visit(cx, cy)
+	for cx != ex || cy != ey {
+		if cy == ey || (cx != ex && nextX < nextY) {
+			cx += stepX
+			nextX += deltaX
+		} else {
+			cy += stepY
+			nextY += deltaY
+		}
+		visit(cx, cy)
+	}
+}
+
+// step returns the direction to step from v0 to v1 along one axis, how far along the segment the first cell border is
+// and how far apart the borders are, both as fractions of the segment
+func step(v0, v1 float64, c int) (dir int, next, delta float64) {
+	d := v1 - v0
+	switch {
+	case d > 0:
+		return 1, (float64(c+1) - v0) / d, 1 / d
+	case d < 0:
+		return -1, (v0 - float64(c)) / -d, 1 / -d
+	}
+	return 0, math.Inf(1), math.Inf(1)
+}
+
+// inRing is a ray casting point in polygon test
+func inRing(ring [][2]float64, lat, lon float64) (in bool) {
+	for i, j := 0, len(ring)-1; i < len(ring); j, i = i, i+1 {
+		a, b := ring[i], ring[j]
+		if (a[0] > lat) != (b[0] > lat) && lon < (b[1]-a[1])*(lat-a[0])/(b[0]-a[0])+a[1] {
+			in = !in
+		}
+	}
+	return
+}
+
+// spanX returns the x coordinates from xmin to xmax, wrapping around the antimeridian if wrap is set
+func spanX(xmin, xmax, zoom int, wrap bool) (xs []int) {
+	if !wrap || xmin <= xmax {
+		// a wrapped span whose ends meet or overlap covers the whole row
+		if wrap {
+			xmin, xmax = 0, 1<<uint(zoom)-1
+		}
+		for x := xmin; x <= xmax; x++ {
+			xs = append(xs, x)
+		}
+		return
+	}
+	for x := xmin; x < 1<<uint(zoom); x++ {
+		xs = append(xs, x)
+	}
+	for x := 0; x <= xmax; x++ {
+		xs = append(xs, x)
+	}
+	return
+}
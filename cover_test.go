@@ -0,0 +1,331 @@
+package tiles
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+func TestTilesForBBox(t *testing.T) {
+	tests := []struct {
+		minLat, minLon, maxLat, maxLon float64
+		zoom                           int
+		tiles                          []Tile
+	}{
+		{MinLat, MinLon, MaxLat, MaxLon, 0, []Tile{{0, 0, 0}}},
+		{MinLat, MinLon, MaxLat, MaxLon, 1, []Tile{{0, 0, 1}, {1, 0, 1}, {0, 1, 1}, {1, 1, 1}}},
+		{10, 10, 20, 20, 1, []Tile{{1, 0, 1}}},
+		{-20, -20, 20, 20, 1, []Tile{{0, 0, 1}, {1, 0, 1}, {0, 1, 1}, {1, 1, 1}}},
+		{10, 170, 20, -170, 2, []Tile{{3, 1, 2}, {0, 1, 2}}},
+		{10, 10, 20, 5, 1, []Tile{{0, 0, 1}, {1, 0, 1}}},
+		{40.6, -74.1, 40.8, -73.9, 10, []Tile{{301, 384, 10}, {301, 385, 10}}},
+		{-1, 179, 1, -179, 8, []Tile{{255, 127, 8}, {0, 127, 8}, {255, 128, 8}, {0, 128, 8}}},
+		// a box ending on tile edges doesn't take in the tiles past them
+		{0, 0, 10, 10, 1, []Tile{{1, 0, 1}}},
+	}
+	errf := "TilesForBBox(%v, %v, %v, %v, %d) -> %v"
+	for _, test := range tests {
+		tiles := TilesForBBox(test.minLat, test.minLon, test.maxLat, test.maxLon, test.zoom)
+		if !tileSliceEqual(tiles, test.tiles) {
+			t.Errorf(errf, test.minLat, test.minLon, test.maxLat, test.maxLon, test.zoom, tiles)
+		}
+	}
+	// a box strictly inside a tile is covered by just that tile, however close it comes to the edges
+	rng := rand.New(rand.NewSource(1))
+	inside := []Tile{{100, 107, 8}}
+	for i := 0; i < 100; i++ {
+		z := 1 + rng.Intn(20)
+		inside = append(inside, Tile{X: rng.Intn(1 << uint(z)), Y: rng.Intn(1 << uint(z)), Z: z})
+	}
+	for _, tile := range inside {
+		minLat, minLon, maxLat, maxLon := tile.Bounds()
+		dLat, dLon := (maxLat-minLat)/1000, (maxLon-minLon)/1000
+		minLat, minLon, maxLat, maxLon = minLat+dLat, minLon+dLon, maxLat-dLat, maxLon-dLon
+		if tiles := TilesForBBox(minLat, minLon, maxLat, maxLon, tile.Z); !tileSliceEqual(tiles, []Tile{tile}) {
+			t.Errorf(errf, minLat, minLon, maxLat, maxLon, tile.Z, tiles)
+		}
+	}
+}
+
+func TestTilesForBBoxSpiral(t *testing.T) {
+	tests := []struct {
+		minLat, minLon, maxLat, maxLon float64
+		zoom                           int
+		first                          []Tile
+	}{
+		{MinLat, MinLon, MaxLat, MaxLon, 0, []Tile{{0, 0, 0}}},
+		{-20, -30, 30, 20, 3, []Tile{{3, 3, 3}}},
+		{1, -100, 5, 60, 3, []Tile{{3, 3, 3}, {4, 3, 3}, {2, 3, 3}, {5, 3, 3}, {1, 3, 3}}},
+		{1, 160, 5, -150, 3, []Tile{{0, 3, 3}, {7, 3, 3}}},
+		{1, 175, 5, -100, 3, []Tile{{0, 3, 3}, {1, 3, 3}, {7, 3, 3}}},
+	}
+	errf := "TilesForBBoxSpiral(%v, %v, %v, %v, %d) -> %v"
+	for _, test := range tests {
+		tiles := TilesForBBoxSpiral(test.minLat, test.minLon, test.maxLat, test.maxLon, test.zoom)
+		all := TilesForBBox(test.minLat, test.minLon, test.maxLat, test.maxLon, test.zoom)
+		if len(tiles) != len(all) || !tileSliceEqual(tiles[:len(test.first)], test.first) {
+			t.Errorf(errf, test.minLat, test.minLon, test.maxLat, test.maxLon, test.zoom, tiles)
+		}
+		for _, tile := range all {
+			if !tileSliceContains(tiles, tile) {
+				t.Errorf(errf, test.minLat, test.minLon, test.maxLat, test.maxLon, test.zoom, tiles)
+			}
+		}
+	}
+}
+
+func TestGridLines(t *testing.T) {
+	lines := GridLines(10, 10, 20, 20, 1)
+	exp := [][2][2]float64{
+		{{MaxLat, 0}, {MaxLat, 180}},
+		{{0, 0}, {0, 180}},
+		{{MaxLat, 0}, {0, 0}},
+		{{MaxLat, 180}, {0, 180}},
+	}
+	if len(lines) != len(exp) {
+		t.Fatalf("GridLines(10, 10, 20, 20, 1) -> %v", lines)
+	}
+	for i, l := range lines {
+		for j := range l {
+			if !floatEquals(l[j][0], exp[i][j][0]) || !floatEquals(l[j][1], exp[i][j][1]) {
+				t.Errorf("GridLines(10, 10, 20, 20, 1) line %d -> %v", i, l)
+			}
+		}
+	}
+	tests := []struct {
+		minLat, minLon, maxLat, maxLon float64
+		zoom                           int
+		n                              int
+	}{
+		{MinLat, MinLon, MaxLat, MaxLon, 0, 4},
+		{MinLat, MinLon, MaxLat, MaxLon, 1, 12},
+		{40.6, -74.1, 40.8, -73.9, 10, 7},
+		{10, 170, 20, -170, 2, 8},
+		{10, 10, 20, 20, 8, 8*9 + 9*8},
+	}
+	errf := "GridLines(%v, %v, %v, %v, %d) -> %d lines"
+	for _, test := range tests {
+		lines := GridLines(test.minLat, test.minLon, test.maxLat, test.maxLon, test.zoom)
+		seen := make(map[[2][2]float64]bool)
+		for _, l := range lines {
+			if seen[l] {
+				t.Errorf("GridLines(%v, %v, %v, %v, %d) repeated %v", test.minLat, test.minLon, test.maxLat, test.maxLon, test.zoom, l)
+			}
+			seen[l] = true
+		}
+		// r rows and c columns of tiles have r+1 rows of c horizontal edges and c+1 columns of r vertical edges
+		if len(lines) != test.n {
+			t.Errorf(errf, test.minLat, test.minLon, test.maxLat, test.maxLon, test.zoom, len(lines))
+		}
+	}
+}
+
+func TestTilesForPolygon(t *testing.T) {
+	// right triangle with the hypotenuse running NW to SE
+	tri := [][2]float64{{40, -110}, {40, -100}, {50, -110}}
+	tests := []struct {
+		ring  [][2]float64
+		zoom  int
+		tiles []Tile
+	}{
+		{nil, 4, nil},
+		{tri[:2], 4, nil},
+		{tri, 4, nil},
+		{tri, 5, []Tile{{X: 6, Y: 11, Z: 5}}},
+		{append(tri, tri[0]), 5, []Tile{{X: 6, Y: 11, Z: 5}}},
+	}
+	errf := "TilesForPolygon(%v, %d) -> %v"
+	for _, test := range tests {
+		tiles := TilesForPolygon(test.ring, test.zoom)
+		if !tileSliceEqual(tiles, test.tiles) {
+			t.Errorf(errf, test.ring, test.zoom, tiles)
+		}
+	}
+	box := [][2]float64{{40, -110}, {40, -100}, {50, -100}, {50, -110}}
+	tri6, box6 := TilesForPolygon(tri, 6), TilesForPolygon(box, 6)
+	if len(tri6) == 0 || len(tri6) >= len(box6) || len(box6) > len(TilesForBBox(40, -110, 50, -100, 6)) {
+		t.Errorf("TilesForPolygon triangle %d tiles, box %d tiles", len(tri6), len(box6))
+	}
+}
+
+func tileSliceEqual(x, y []Tile) bool {
+	if len(x) != len(y) {
+		return false
+	}
+	for i, v := range x {
+		if y[i] != v {
+			return false
+		}
+	}
+	return true
+}
+
+func TestDistanceToBounds(t *testing.T) {
+	// brute force against points along the tile's edges, which is where the closest point is for a coordinate outside it
+	rng := rand.New(rand.NewSource(1))
+	const steps = 1000
+	for i := 0; i < 200; i++ {
+		z := 1 + rng.Intn(6)
+		tile := Tile{X: rng.Intn(1 << uint(z)), Y: rng.Intn(1 << uint(z)), Z: z}
+		lat, lon := rng.Float64()*170-85, rng.Float64()*360-180
+		minLat, minLon, maxLat, maxLon := tile.Bounds()
+		min := math.Inf(1)
+		for s := 0; s <= steps; s++ {
+			f := float64(s) / steps
+			sLat, sLon := minLat+f*(maxLat-minLat), minLon+f*(maxLon-minLon)
+			for _, p := range [][2]float64{{sLat, minLon}, {sLat, maxLon}, {minLat, sLon}, {maxLat, sLon}} {
+				min = math.Min(min, DistanceMeters(lat, lon, p[0], p[1]))
+			}
+		}
+		// the sampled points are at most a step apart, so the closest one may be up to a step further than the edge
+		gap := math.Max(DistanceMeters(minLat, minLon, minLat+(maxLat-minLat)/steps, minLon), DistanceMeters(0, minLon, 0, minLon+(maxLon-minLon)/steps))
+		if tile.Contains(lat, lon) {
+			min = 0
+		}
+		if d := distanceToBounds(lat, lon, tile); d > min+1e-6 || d < min-gap {
+			t.Errorf("distanceToBounds(%v, %v, %v) -> %v, edges are %v away", lat, lon, tile, d, min)
+		}
+	}
+}
+
+func TestTilesForCircle(t *testing.T) {
+	esb := FromCoordinate(40.7484, -73.9857, 18)
+	tests := []struct {
+		lat, lon, radius float64
+		zoom             int
+		tiles            []Tile
+	}{
+		{40.7484, -73.9857, 0, 18, []Tile{esb}},
+		{40.7484, -73.9857, 1, 18, []Tile{esb}},
+		{0, 0, 1000, 10, []Tile{{511, 511, 10}, {512, 511, 10}, {511, 512, 10}, {512, 512, 10}}},
+		{0.1, 179.9, 50000, 8, []Tile{{255, 127, 8}, {0, 127, 8}, {255, 128, 8}, {0, 128, 8}}},
+		{84, 0, 1000000, 2, []Tile{{0, 0, 2}, {1, 0, 2}, {2, 0, 2}, {3, 0, 2}}},
+		{0, 0, 1e9, 1, []Tile{{0, 0, 1}, {1, 0, 1}, {0, 1, 1}, {1, 1, 1}}},
+	}
+	errf := "TilesForCircle(%v, %v, %v, %d) -> %v"
+	for _, test := range tests {
+		tiles := TilesForCircle(test.lat, test.lon, test.radius, test.zoom)
+		if !tileSliceEqual(tiles, test.tiles) {
+			t.Errorf(errf, test.lat, test.lon, test.radius, test.zoom, tiles)
+		}
+	}
+	// the circle drops the bbox corners but keeps every tile the circle passes through
+	lat, lon, radius := 40.7484, -73.9857, 2000.0
+	circle := TilesForCircle(lat, lon, radius, 16)
+	box := TilesForBBox(40.73, -74.01, 40.767, -73.96, 16)
+	if len(circle) == 0 || len(circle) >= len(box) {
+		t.Errorf("TilesForCircle %d tiles, bbox %d tiles", len(circle), len(box))
+	}
+	for _, tile := range circle {
+		if d := distanceToBounds(lat, lon, tile); d > radius {
+			t.Errorf("TilesForCircle %v is %vm away", tile, d)
+		}
+	}
+	for _, tile := range box {
+		if d := distanceToTile(lat, lon, tile); d < radius && !tileSliceContains(circle, tile) {
+			t.Errorf("TilesForCircle missing %v, center is %vm away", tile, d)
+		}
+	}
+}
+
+func tileSliceContains(tiles []Tile, t Tile) bool {
+	for _, tile := range tiles {
+		if tile == t {
+			return true
+		}
+	}
+	return false
+}
+
+func TestTilesForLine(t *testing.T) {
+	tests := []struct {
+		points [][2]float64
+		zoom   int
+		tiles  []Tile
+	}{
+		{nil, 10, nil},
+		{[][2]float64{{40.7, -74.1}}, 10, []Tile{{301, 385, 10}}},
+		{[][2]float64{{51.5, -0.2}, {51.5, 0.2}}, 10, []Tile{{511, 340, 10}, {512, 340, 10}}},
+		{[][2]float64{{40.6, -74.1}, {40.8, -73.9}}, 10, []Tile{{301, 385, 10}, {301, 384, 10}}},
+		{[][2]float64{{40.6, -74.1}, {40.8, -73.9}, {40.6, -74.1}}, 10, []Tile{{301, 385, 10}, {301, 384, 10}}},
+		{[][2]float64{{0.1, 179.9}, {0.1, -179.9}}, 8, []Tile{{255, 127, 8}, {0, 127, 8}}},
+		{[][2]float64{{0.1, -179.9}, {0.1, 179.9}}, 8, []Tile{{0, 127, 8}, {255, 127, 8}}},
+		{[][2]float64{{-1, 179}, {3, -179}}, 8, []Tile{{255, 128, 8}, {255, 127, 8}, {0, 127, 8}, {0, 126, 8}, {0, 125, 8}}},
+	}
+	errf := "TilesForLine(%v, %d) -> %v"
+	for _, test := range tests {
+		if tiles := TilesForLine(test.points, test.zoom); !tileSliceEqual(tiles, test.tiles) {
+			t.Errorf(errf, test.points, test.zoom, tiles)
+		}
+	}
+	// a long diagonal passes through the same tiles as dense samples along it, each one adjacent to the last
+	line := [][2]float64{{40.7484, -73.9857}, {51.5007, -0.1246}}
+	tiles := TilesForLine(line, 8)
+	n := float64(1 << 8)
+	x0, y0 := tileSpace(line[0][0], line[0][1], n)
+	x1, y1 := tileSpace(line[1][0], line[1][1], n)
+	sampled := make(map[Tile]struct{})
+	for i := 0; i <= 100000; i++ {
+		f := float64(i) / 100000
+		sampled[Tile{X: int(x0 + f*(x1-x0)), Y: int(y0 + f*(y1-y0)), Z: 8}] = struct{}{}
+	}
+	if len(tiles) != len(sampled) {
+		t.Errorf("TilesForLine %d tiles, sampled %d", len(tiles), len(sampled))
+	}
+	for i, tile := range tiles {
+		if _, ok := sampled[tile]; !ok {
+			t.Errorf("TilesForLine %v wasn't sampled", tile)
+		}
+		if i > 0 {
+			if dx, dy := tile.X-tiles[i-1].X, tile.Y-tiles[i-1].Y; dx*dx+dy*dy != 1 {
+				t.Errorf("TilesForLine %v doesn't follow %v", tile, tiles[i-1])
+			}
+		}
+	}
+}
+
+func TestQuadkeyCover(t *testing.T) {
+	tests := []struct {
+		minLat, minLon, maxLat, maxLon float64
+		zoom                           int
+		cover                          []Quadkey
+	}{
+		{MinLat, MinLon, MaxLat, MaxLon, 0, []Quadkey{""}},
+		{MinLat, MinLon, MaxLat, MaxLon, 5, []Quadkey{""}},
+		{10, 10, 20, 20, 1, []Quadkey{"1"}},
+		{1, -179, MaxLat, 179, 2, []Quadkey{"0", "1"}},
+		{1, -179, MaxLat, -1, 3, []Quadkey{"0"}},
+		{-1, -1, 1, 1, 2, []Quadkey{"03", "12", "21", "30"}},
+	}
+	errf := "QuadkeyCover(%v, %v, %v, %v, %d) -> %v"
+	for _, test := range tests {
+		cover := QuadkeyCover(test.minLat, test.minLon, test.maxLat, test.maxLon, test.zoom)
+		if !qkSliceEqual(cover, test.cover) {
+			t.Errorf(errf, test.minLat, test.minLon, test.maxLat, test.maxLon, test.zoom, cover)
+		}
+	}
+	// the cover spans exactly the bbox's tiles without overlapping
+	minLat, minLon, maxLat, maxLon := 40.6, -74.3, 41.0, -73.7
+	cover := QuadkeyCover(minLat, minLon, maxLat, maxLon, 14)
+	tiles := TilesForBBox(minLat, minLon, maxLat, maxLon, 14)
+	if len(cover) >= len(tiles) {
+		t.Errorf("QuadkeyCover %d cells for %d tiles", len(cover), len(tiles))
+	}
+	set := NewTileSet()
+	n := 0
+	for _, qk := range cover {
+		if set.Contains(qk.ToTile()) {
+			t.Errorf("QuadkeyCover %s overlaps", qk)
+		}
+		set.Add(qk.ToTile())
+		n += 1 << uint(2*(14-qk.Level()))
+	}
+	if n != len(tiles) {
+		t.Errorf("QuadkeyCover covers %d tiles, expected %d", n, len(tiles))
+	}
+	for _, tile := range tiles {
+		if !set.Contains(tile) {
+			t.Errorf("QuadkeyCover missing %v", tile)
+		}
+	}
+}
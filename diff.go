@@ -0,0 +1,210 @@
+package tiles
+
+import (
+	"encoding/binary"
+	"fmt"
+	"hash/fnv"
+	"reflect"
+	"sort"
+)
+
+// Diff returns the entries in b that aren't in a as added and those in a that aren't in b as removed, both in quadkey order.
+// Entries match when they were added to the same tile with reflect.DeepEqual values, and a value added to a tile n times in one index
+// and m times in the other shows up |n - m| times. Each index is copied under its readlock in turn, so the two locks are never held together
+func Diff(a, b *KeysetIndex) (added, removed []Entry) {
+	ak, av := a.sortedCopy()
+	bk, bv := b.sortedCopy()
+	i, j := 0, 0
+	for i < len(ak) || j < len(bk) {
+		switch {
+		case j == len(bk) || (i < len(ak) && ak[i].qk < bk[j].qk):
+			qk, vals, n := group(ak, av, i)
+			removed = appendEntries(removed, qk, vals)
+			i = n
+		case i == len(ak) || bk[j].qk < ak[i].qk:
+			qk, vals, n := group(bk, bv, j)
+			added = appendEntries(added, qk, vals)
+			j = n
+		default:
+			qk, avals, n := group(ak, av, i)
+			_, bvals, m := group(bk, bv, j)
+			only, other := diffValues(avals, bvals)
+			removed = appendEntries(removed, qk, only)
+			added = appendEntries(added, qk, other)
+			i, j = n, m
+		}
+	}
+	return
+}
+
+// Changes is the result of Diff kept together, so a region's share of it can be picked out with UnderTile
+type Changes struct {
+	Added, Removed []Entry
+}
+
+// DiffChanges returns Diff(a, b) as Changes
+func DiffChanges(a, b *KeysetIndex) Changes {
+	added, removed := Diff(a, b)
+	return Changes{Added: added, Removed: removed}
+}
+
+// UnderTile returns the values of the added and removed entries at t or a tile under it, in the order of the Changes.
+// It's a filter over all of the entries, so it works on Changes that weren't made by DiffChanges too
+func (c Changes) UnderTile(t Tile) (added, removed []interface{}) {
+	qk := packTile(t)
+	under := func(entries []Entry) (vals []interface{}) {
+		for _, e := range entries {
+			if k := packTile(e.Tile); k == qk || k.HasParent(qk) {
+				vals = append(vals, e.Value)
+			}
+		}
+		return
+	}
+	return under(c.Added), under(c.Removed)
+}
+
+// Fingerprint returns FingerprintFunc with each value hashed by its %#v formatting, which is stable for plain values and maps
+// but hashes pointers by address, so use FingerprintFunc for values that are or contain pointers
+func (idx *KeysetIndex) Fingerprint() uint64 {
+	return idx.FingerprintFunc(func(val interface{}) uint64 {
+		h := fnv.New64a()
+		fmt.Fprintf(h, "%#v", val)
+		return h.Sum64()
+	})
+}
+
+// FingerprintFunc returns a 64-bit FNV-1a hash of the index's quadkeys in sorted order, each followed by its values hashed with h.
+// The value hashes at a quadkey are sorted, so indexes with the same values at the same quadkeys have the same fingerprint whatever order they were added in.
+// The index is copied under its readlock, so h is called after it's released
+func (idx *KeysetIndex) FingerprintFunc(h func(val interface{}) uint64) uint64 {
+	keys, values := idx.sortedCopy()
+	sum := fnv.New64a()
+	var buf [8]byte
+	write := func(u uint64) {
+		binary.BigEndian.PutUint64(buf[:], u)
+		sum.Write(buf[:])
+	}
+	var hashes []uint64
+	for i := 0; i < len(keys); {
+		qk, vals, next := group(keys, values, i)
+		hashes = hashes[:0]
+		for _, v := range vals {
+			hashes = append(hashes, h(v))
+		}
+		// keys left without values don't change what the index holds
+		if len(hashes) > 0 {
+			sort.Slice(hashes, func(a, b int) bool { return hashes[a] < hashes[b] })
+			write(uint64(qk))
+			write(uint64(len(hashes)))
+			for _, vh := range hashes {
+				write(vh)
+			}
+		}
+		i = next
+	}
+	return sum.Sum64()
+}
+
+// Joined holds the values of each index under a tile returned by Join
+type Joined struct {
+	A, B []interface{}
+}
+
+// Join returns the values of a and b under each tile at the zoom that's populated in either index, co-walking their sorted keys.
+// Like Densities, values added at a shallower zoom aren't under any single tile at the zoom, so they're left out.
+// Each index is copied under its readlock in turn, so the two locks are never held together. A negative zoom is treated as 0
+func Join(a, b *KeysetIndex, zoom int) map[Tile]Joined {
+	if zoom < 0 {
+		zoom = 0
+	}
+	ak, av := a.sortedCopy()
+	bk, bv := b.sortedCopy()
+	joined := make(map[Tile]Joined)
+	i, j := skipAbove(ak, 0, zoom), skipAbove(bk, 0, zoom)
+	for i < len(ak) || j < len(bk) {
+		var jv Joined
+		var qk pkey
+		switch {
+		case j == len(bk) || (i < len(ak) && ak[i].qk.Parent(zoom) < bk[j].qk.Parent(zoom)):
+			qk, jv.A, i = groupAt(ak, av, i, zoom)
+		case i == len(ak) || bk[j].qk.Parent(zoom) < ak[i].qk.Parent(zoom):
+			qk, jv.B, j = groupAt(bk, bv, j, zoom)
+		default:
+			qk, jv.A, i = groupAt(ak, av, i, zoom)
+			_, jv.B, j = groupAt(bk, bv, j, zoom)
+		}
+		joined[qk.ToTile()] = jv
+	}
+	return joined
+}
+
+// groupAt returns the values under the tile at the zoom of keys[i] and the index of the next key deep enough to be under a tile at the zoom
+func groupAt(keys []qkey, values [][]interface{}, i, zoom int) (qk pkey, vals []interface{}, next int) {
+	qk = keys[i].qk.Parent(zoom)
+	for next = i; next < len(keys) && keys[next].qk.Parent(zoom) == qk; next = skipAbove(keys, next+1, zoom) {
+		vals = append(vals, values[keys[next].v]...)
+	}
+	return
+}
+
+// skipAbove returns the index of the first key from i that's at the zoom or deeper
+func skipAbove(keys []qkey, i, zoom int) int {
+	for i < len(keys) && keys[i].qk.Level() < zoom {
+		i++
+	}
+	return i
+}
+
+// sortedCopy returns a copy of the sorted keys and the values they point to
+func (idx *KeysetIndex) sortedCopy() (keys []qkey, values [][]interface{}) {
+	idx.rlockSorted()
+	defer idx.RUnlock()
+	keys = make([]qkey, len(idx.keys))
+	copy(keys, idx.keys)
+	values = make([][]interface{}, len(idx.values))
+	copy(values, idx.values)
+	return
+}
+
+// group returns the values of the run of keys equal to keys[i] and the index after the run
+func group(keys []qkey, values [][]interface{}, i int) (qk pkey, vals []interface{}, next int) {
+	qk = keys[i].qk
+	for next = i; next < len(keys) && keys[next].qk == qk; next++ {
+		vals = append(vals, values[keys[next].v]...)
+	}
+	return
+}
+
+// diffValues returns the values only in a and those only in b, matching each value at most once
+func diffValues(a, b []interface{}) (onlyA, onlyB []interface{}) {
+	matched := make([]bool, len(b))
+	for _, av := range a {
+		found := false
+		for j, bv := range b {
+			if !matched[j] && reflect.DeepEqual(av, bv) {
+				matched[j], found = true, true
+				break
+			}
+		}
+		if !found {
+			onlyA = append(onlyA, av)
+		}
+	}
+	for j, bv := range b {
+		if !matched[j] {
+			onlyB = append(onlyB, bv)
+		}
+	}
+	return
+}
+
+func appendEntries(entries []Entry, qk pkey, vals []interface{}) []Entry {
+	if len(vals) == 0 {
+		return entries
+	}
+	t := qk.ToTile()
+	for _, v := range vals {
+		entries = append(entries, Entry{Tile: t, Value: v})
+	}
+	return entries
+}
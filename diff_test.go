@@ -0,0 +1,156 @@
+package tiles
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDiff(t *testing.T) {
+	esb := FromCoordinate(40.7484, -73.9857, 18)
+	sol := FromCoordinate(40.6892, -74.0445, 18)
+	bbn := FromCoordinate(51.5007, -0.1246, 18)
+	nyc := Tile{X: 75, Y: 96, Z: 8}
+	a := &KeysetIndex{}
+	a.Add(esb, "EmpireStateBuilding", "ChryslerBuilding")
+	a.Add(sol, "StatueOfLiberty")
+	a.Add(nyc, landmark{"NewYork", 0}, "Manhattan", "Manhattan")
+	b := &KeysetIndex{}
+	b.Add(bbn, "BigBen")
+	b.Add(nyc, "Manhattan", landmark{"NewYork", 0})
+	b.Add(esb, "EmpireStateBuilding")
+	b.Add(esb, "Macy's")
+	added, removed := Diff(a, b)
+	expAdded := []Entry{{bbn, "BigBen"}, {esb, "Macy's"}}
+	expRemoved := []Entry{{nyc, "Manhattan"}, {esb, "ChryslerBuilding"}, {sol, "StatueOfLiberty"}}
+	if !reflect.DeepEqual(added, expAdded) || !reflect.DeepEqual(removed, expRemoved) {
+		t.Errorf("Diff(a, b) -> %v, %v", added, removed)
+	}
+	if added, removed := Diff(b, a); !reflect.DeepEqual(added, expRemoved) || !reflect.DeepEqual(removed, expAdded) {
+		t.Errorf("Diff(b, a) -> %v, %v", added, removed)
+	}
+	if added, removed := Diff(a, a); added != nil || removed != nil {
+		t.Errorf("Diff(a, a) -> %v, %v", added, removed)
+	}
+	if added, removed := Diff(&KeysetIndex{}, a); len(added) != a.Len() || removed != nil {
+		t.Errorf("Diff(empty, a) -> %v, %v", added, removed)
+	}
+}
+
+func TestChangesUnderTile(t *testing.T) {
+	esb := FromCoordinate(40.7484, -73.9857, 18)
+	sol := FromCoordinate(40.6892, -74.0445, 18)
+	bbn := FromCoordinate(51.5007, -0.1246, 18)
+	nyc := Tile{X: 75, Y: 96, Z: 8}
+	a := &KeysetIndex{}
+	a.Add(esb, "EmpireStateBuilding", "ChryslerBuilding")
+	a.Add(sol, "StatueOfLiberty")
+	a.Add(Tile{}, "World")
+	b := &KeysetIndex{}
+	b.Add(bbn, "BigBen")
+	b.Add(esb, "EmpireStateBuilding", "Macy's")
+	b.Add(nyc, "Manhattan")
+	c := DiffChanges(a, b)
+	if added, removed := Diff(a, b); !reflect.DeepEqual(c, Changes{added, removed}) {
+		t.Error("DiffChanges(a, b) -> ", c)
+	}
+	tests := []struct {
+		tile           Tile
+		added, removed []interface{}
+	}{
+		{nyc, []interface{}{"Manhattan", "Macy's"}, []interface{}{"ChryslerBuilding", "StatueOfLiberty"}},
+		{esb, []interface{}{"Macy's"}, []interface{}{"ChryslerBuilding"}},
+		{Tile{}, []interface{}{"BigBen", "Manhattan", "Macy's"}, []interface{}{"World", "ChryslerBuilding", "StatueOfLiberty"}},
+		{Tile{X: 106, Y: 194, Z: 9}, nil, nil},
+	}
+	errf := "Changes.UnderTile(%v) -> %v, %v"
+	for _, test := range tests {
+		if added, removed := c.UnderTile(test.tile); !reflect.DeepEqual(added, test.added) || !reflect.DeepEqual(removed, test.removed) {
+			t.Errorf(errf, test.tile, added, removed)
+		}
+	}
+}
+
+func TestJoin(t *testing.T) {
+	esb := FromCoordinate(40.7484, -73.9857, 18)
+	sol := FromCoordinate(40.6892, -74.0445, 18)
+	bbn := FromCoordinate(51.5007, -0.1246, 18)
+	nyc := Tile{X: 75, Y: 96, Z: 8}
+	lon := bbn.Quadkey()[:8].ToTile()
+	shops := &KeysetIndex{}
+	shops.Add(esb, "Macy's")
+	shops.Add(sol, "GiftShop")
+	shops.Add(Tile{}, "Online")
+	customers := &KeysetIndex{}
+	customers.Add(bbn, "Alice")
+	customers.Add(esb, "Bob")
+	customers.Add(nyc, "Carol")
+	exp := map[Tile]Joined{
+		nyc: {A: []interface{}{"Macy's", "GiftShop"}, B: []interface{}{"Carol", "Bob"}},
+		lon: {B: []interface{}{"Alice"}},
+	}
+	if j := Join(shops, customers, 8); !reflect.DeepEqual(j, exp) {
+		t.Errorf("Join(shops, customers, 8) -> %v", j)
+	}
+	exp = map[Tile]Joined{
+		esb: {A: []interface{}{"Macy's"}, B: []interface{}{"Bob"}},
+		sol: {A: []interface{}{"GiftShop"}},
+		bbn: {B: []interface{}{"Alice"}},
+	}
+	if j := Join(shops, customers, 18); !reflect.DeepEqual(j, exp) {
+		t.Errorf("Join(shops, customers, 18) -> %v", j)
+	}
+	exp = map[Tile]Joined{{}: {A: []interface{}{"Online", "Macy's", "GiftShop"}}}
+	if j := Join(shops, &KeysetIndex{}, 0); !reflect.DeepEqual(j, exp) {
+		t.Errorf("Join(shops, empty, 0) -> %v", j)
+	}
+	if j := Join(shops, customers, 19); len(j) != 0 {
+		t.Errorf("Join(shops, customers, 19) -> %v", j)
+	}
+}
+
+func TestKeysetIndexFingerprint(t *testing.T) {
+	esb := FromCoordinate(40.7484, -73.9857, 18)
+	sol := FromCoordinate(40.6892, -74.0445, 18)
+	nyc := Tile{X: 75, Y: 96, Z: 8}
+	a := &KeysetIndex{}
+	a.Add(esb, "EmpireStateBuilding", landmark{"ChryslerBuilding", 319})
+	a.Add(sol, "StatueOfLiberty")
+	a.Add(nyc)
+	b := &KeysetIndex{}
+	b.Add(sol, "StatueOfLiberty")
+	b.Add(esb, landmark{"ChryslerBuilding", 319})
+	b.Add(esb, "EmpireStateBuilding")
+	if fa, fb := a.Fingerprint(), b.Fingerprint(); fa != fb {
+		t.Errorf("Fingerprint of the same contents differs: %x != %x", fa, fb)
+	}
+	if fa, fe := a.Fingerprint(), (&KeysetIndex{}).Fingerprint(); fa == fe {
+		t.Errorf("Fingerprint matches an empty index: %x", fa)
+	}
+	changes := []func(idx *KeysetIndex){
+		func(idx *KeysetIndex) { idx.Add(nyc, "NewYork") },
+		func(idx *KeysetIndex) { idx.Delete(sol, "StatueOfLiberty") },
+		func(idx *KeysetIndex) { idx.Add(sol, "StatueOfLiberty") },
+		func(idx *KeysetIndex) {
+			idx.Delete(sol, "StatueOfLiberty")
+			idx.Add(sol.Parent(), "StatueOfLiberty")
+		},
+	}
+	for i, change := range changes {
+		c := a.Snapshot()
+		change(c)
+		if fa, fc := a.Fingerprint(), c.Fingerprint(); fa == fc {
+			t.Errorf("Fingerprint after change %d didn't change: %x", i, fc)
+		}
+	}
+	calls := 0
+	height := func(val interface{}) uint64 {
+		calls++
+		if l, ok := val.(landmark); ok {
+			return uint64(l.Height)
+		}
+		return 0
+	}
+	if fa, fb := a.FingerprintFunc(height), b.FingerprintFunc(height); fa != fb || calls != 6 {
+		t.Errorf("FingerprintFunc -> %x, %x after %d calls", fa, fb, calls)
+	}
+}
@@ -0,0 +1,164 @@
+package tiles
+
+import (
+	"encoding/csv"
+	"encoding/gob"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// gobKey is the serialized form of a key and its values
+type gobKey struct {
+	Quadkey Quadkey
+	Values  []interface{}
+}
+
+// WriteTo writes the index to w using encoding/gob and returns the number of bytes written.
+// Values are encoded as interface{}, so callers must gob.Register their concrete value types
+func (idx *KeysetIndex) WriteTo(w io.Writer) (int64, error) {
+	idx.rlockSorted()
+	keys := make([]gobKey, len(idx.keys))
+	for i, k := range idx.keys {
+		keys[i] = gobKey{Quadkey: k.qk.Quadkey(), Values: idx.values[k.v]}
+	}
+	idx.RUnlock()
+	cw := &countingWriter{w: w}
+	err := gob.NewEncoder(cw).Encode(keys)
+	return cw.n, err
+}
+
+// ReadFrom replaces the contents of the index with those written by WriteTo, dropping any upserted ids and returns the number of bytes read.
+// The decoder may buffer, so more bytes than the encoded index can be consumed from r.
+// Values are decoded as interface{}, so callers must gob.Register their concrete value types
+func (idx *KeysetIndex) ReadFrom(r io.Reader) (int64, error) {
+	cr := &countingReader{r: r}
+	var keys []gobKey
+	if err := gob.NewDecoder(cr).Decode(&keys); err != nil {
+		return cr.n, err
+	}
+	qks := make([]qkey, len(keys))
+	values := make([][]interface{}, len(keys))
+	for i, k := range keys {
+		qk, err := packQuadkey(k.Quadkey)
+		if err != nil {
+			return cr.n, err
+		}
+		qks[i] = qkey{qk: qk, v: i}
+		values[i] = k.Values
+	}
+	idx.Lock()
+	defer idx.Unlock()
+	for i := range qks {
+		qks[i].qk, qks[i].at = idx.truncate(qks[i].qk), idx.stamp()
+	}
+	idx.keys = qks
+	idx.values = values
+	idx.ids = nil
+	idx.sorted = false
+	return cr.n, nil
+}
+
+// jsonEntry is the serialized form of a single value
+type jsonEntry struct {
+	Quadkey Quadkey     `json:"quadkey"`
+	Value   interface{} `json:"value"`
+}
+
+// MarshalJSON encodes the index as an array of {"quadkey": ..., "value": ...} objects in quadkey order.
+// An empty index encodes to []
+func (idx *KeysetIndex) MarshalJSON() ([]byte, error) {
+	idx.rlockSorted()
+	defer idx.RUnlock()
+	entries := []jsonEntry{}
+	for _, k := range idx.keys {
+		for _, v := range idx.values[k.v] {
+			entries = append(entries, jsonEntry{Quadkey: k.qk.Quadkey(), Value: v})
+		}
+	}
+	return json.Marshal(entries)
+}
+
+// UnmarshalJSON replaces the contents of the index with those encoded by MarshalJSON, dropping any upserted ids.
+// Values are decoded with encoding/json's default types for interface{}
+func (idx *KeysetIndex) UnmarshalJSON(data []byte) error {
+	var entries []jsonEntry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return err
+	}
+	keys := make([]qkey, len(entries))
+	values := make([][]interface{}, len(entries))
+	for i, e := range entries {
+		qk, err := packQuadkey(e.Quadkey)
+		if err != nil {
+			return err
+		}
+		keys[i] = qkey{qk: qk, v: i}
+		values[i] = []interface{}{e.Value}
+	}
+	idx.Lock()
+	defer idx.Unlock()
+	for i := range keys {
+		keys[i].qk, keys[i].at = idx.truncate(keys[i].qk), idx.stamp()
+	}
+	idx.keys = keys
+	idx.values = values
+	idx.ids = nil
+	idx.sorted = false
+	return nil
+}
+
+// WriteCSV writes a quadkey,z,x,y,value header and a row for every value in quadkey order, with each value stringified by format.
+// If format is nil values are written with fmt.Sprint. The values are copied under the readlock and written after it's released,
+// so format may be slow but must not rely on the index staying the same
+func (idx *KeysetIndex) WriteCSV(w io.Writer, format func(val interface{}) string) error {
+	if format == nil {
+		format = func(val interface{}) string { return fmt.Sprint(val) }
+	}
+	idx.rlockSorted()
+	keys := make([]pkey, len(idx.keys))
+	values := make([][]interface{}, len(idx.keys))
+	for i, k := range idx.keys {
+		keys[i], values[i] = k.qk, idx.values[k.v]
+	}
+	idx.RUnlock()
+	cw := csv.NewWriter(w)
+	if err := cw.Write([]string{"quadkey", "z", "x", "y", "value"}); err != nil {
+		return err
+	}
+	for i, qk := range keys {
+		t := qk.ToTile()
+		row := []string{string(qk.Quadkey()), strconv.Itoa(t.Z), strconv.Itoa(t.X), strconv.Itoa(t.Y), ""}
+		for _, v := range values[i] {
+			row[4] = format(v)
+			if err := cw.Write(row); err != nil {
+				return err
+			}
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	n, err := w.w.Write(p)
+	w.n += int64(n)
+	return n, err
+}
+
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	r.n += int64(n)
+	return n, err
+}
@@ -0,0 +1,110 @@
+package tiles
+
+import (
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestKeysetIndexWriteToReadFrom(t *testing.T) {
+	gob.Register(landmark{})
+	idx := &KeysetIndex{}
+	esb := FromCoordinate(40.7484, -73.9857, 18)
+	sol := FromCoordinate(40.6892, -74.0445, 18)
+	bbn := FromCoordinate(51.5007, -0.1246, 18)
+	idx.Add(esb, landmark{"EmpireStateBuilding", 443})
+	idx.Add(sol, landmark{"StatueOfLiberty", 93}, "LibertyIsland")
+	idx.Add(bbn, "BigBen")
+	var buf bytes.Buffer
+	n, err := idx.WriteTo(&buf)
+	if err != nil || n != int64(buf.Len()) {
+		t.Fatalf("KeysetIndex.WriteTo() -> %d, %v", n, err)
+	}
+	cp := &KeysetIndex{}
+	if _, err := cp.ReadFrom(&buf); err != nil {
+		t.Fatal("KeysetIndex.ReadFrom() -> ", err)
+	}
+	for _, tile := range []Tile{esb, sol, bbn, {X: 75, Y: 96, Z: 8}, {X: 106, Y: 194, Z: 9}, {}} {
+		if !reflect.DeepEqual(idx.Values(tile), cp.Values(tile)) {
+			t.Errorf("Values(%+v) %v -> %v", tile, idx.Values(tile), cp.Values(tile))
+		}
+	}
+}
+
+func TestKeysetIndexReadFromInvalid(t *testing.T) {
+	idx := &KeysetIndex{}
+	if _, err := idx.ReadFrom(bytes.NewBufferString("not a gob")); err == nil {
+		t.Error("KeysetIndex.ReadFrom() did not error on invalid input")
+	}
+}
+
+func TestKeysetIndexJSON(t *testing.T) {
+	idx := &KeysetIndex{}
+	b, err := json.Marshal(idx)
+	if err != nil || string(b) != "[]" {
+		t.Errorf("json.Marshal(empty) -> %s, %v", b, err)
+	}
+	esb := FromCoordinate(40.7484, -73.9857, 18)
+	sol := FromCoordinate(40.6892, -74.0445, 18)
+	bbn := FromCoordinate(51.5007, -0.1246, 18)
+	idx.Add(esb, "EmpireStateBuilding")
+	idx.Add(sol, "StatueOfLiberty", "LibertyIsland")
+	idx.Add(bbn, "BigBen")
+	b, err = json.Marshal(idx)
+	if err != nil {
+		t.Fatal("json.Marshal() -> ", err)
+	}
+	exp := `[{"quadkey":"031313131130102103","value":"BigBen"},` +
+		`{"quadkey":"032010110132023321","value":"EmpireStateBuilding"},` +
+		`{"quadkey":"032010110301120232","value":"StatueOfLiberty"},` +
+		`{"quadkey":"032010110301120232","value":"LibertyIsland"}]`
+	if string(b) != exp {
+		t.Errorf("json.Marshal() -> %s", b)
+	}
+	cp := &KeysetIndex{}
+	if err := json.Unmarshal(b, cp); err != nil {
+		t.Fatal("json.Unmarshal() -> ", err)
+	}
+	if cp.sorted {
+		t.Error("json.Unmarshal() did not reset the sorted flag")
+	}
+	for _, tile := range []Tile{esb, sol, bbn, {X: 75, Y: 96, Z: 8}, {X: 106, Y: 194, Z: 9}} {
+		if !reflect.DeepEqual(idx.Values(tile), cp.Values(tile)) {
+			t.Errorf("Values(%+v) %v -> %v", tile, idx.Values(tile), cp.Values(tile))
+		}
+	}
+	if err := json.Unmarshal([]byte(`[{"quadkey":"0124","value":1}]`), cp); err == nil {
+		t.Error("json.Unmarshal() did not error on an invalid quadkey")
+	}
+}
+
+func TestKeysetIndexWriteCSV(t *testing.T) {
+	idx := &KeysetIndex{}
+	var buf bytes.Buffer
+	if err := idx.WriteCSV(&buf, nil); err != nil || buf.String() != "quadkey,z,x,y,value\n" {
+		t.Errorf("empty WriteCSV -> %q, %v", buf.String(), err)
+	}
+	idx.Add(Tile{X: 26, Y: 48, Z: 7}, landmark{"Denver", 1609}, landmark{"Boulder, CO", 1655})
+	idx.Add(Tile{X: 1, Y: 0, Z: 1}, landmark{"North \"East\"", 0})
+	tests := []struct {
+		format func(val interface{}) string
+		csv    string
+	}{
+		{
+			func(val interface{}) string { return val.(landmark).Name },
+			"quadkey,z,x,y,value\n0231010,7,26,48,Denver\n0231010,7,26,48,\"Boulder, CO\"\n1,1,1,0,\"North \"\"East\"\"\"\n",
+		},
+		{
+			nil,
+			"quadkey,z,x,y,value\n0231010,7,26,48,{Denver 1609}\n0231010,7,26,48,\"{Boulder, CO 1655}\"\n1,1,1,0,\"{North \"\"East\"\" 0}\"\n",
+		},
+	}
+	for _, test := range tests {
+		buf.Reset()
+		if err := idx.WriteCSV(&buf, test.format); err != nil || buf.String() != test.csv {
+			t.Errorf("WriteCSV -> %q, %v", buf.String(), err)
+		}
+	}
+}
@@ -0,0 +1,50 @@
+package tiles
+
+import "time"
+
+// NewKeysetIndexExpiring returns an empty KeysetIndex like NewKeysetIndex that records when each key was written so Expire can drop stale values.
+// Add, AddSorted, AddBatch, LoadFrom and Upsert stamp the keys they write, and Merge, ReadFrom and UnmarshalJSON stamp the keys they load with the time of the load.
+// Re-upserting an id restamps it and keys merged by Compact keep the latest stamp
+func NewKeysetIndexExpiring(capacity int) *KeysetIndex {
+	idx := NewKeysetIndex(capacity)
+	idx.stamped = true
+	return idx
+}
+
+// Expire removes the keys written before olderThan and their values in a single locked pass, dropping the ids of removed upserts.
+// Returns the number of values removed, which is always 0 if the index wasn't made by NewKeysetIndexExpiring
+func (idx *KeysetIndex) Expire(olderThan time.Time) (n int) {
+	idx.Lock()
+	defer idx.Unlock()
+	if !idx.stamped {
+		return 0
+	}
+	cutoff := olderThan.UnixNano()
+	keys := idx.keys[:0]
+	values := make([][]interface{}, 0, len(idx.values))
+	slots := make(map[int]int, len(idx.ids))
+	for _, k := range idx.keys {
+		if k.at < cutoff {
+			n += len(idx.values[k.v])
+			continue
+		}
+		values = append(values, idx.values[k.v])
+		keys = append(keys, qkey{qk: k.qk, v: len(values) - 1, at: k.at})
+		slots[k.v] = len(values) - 1
+	}
+	idx.keys = keys
+	idx.values = values
+	idx.reslot(func(v int) (s int, ok bool) {
+		s, ok = slots[v]
+		return
+	})
+	return
+}
+
+// stamp returns the time to record for a key written now, or 0 if the index isn't stamped
+func (idx *KeysetIndex) stamp() int64 {
+	if !idx.stamped {
+		return 0
+	}
+	return time.Now().UnixNano()
+}
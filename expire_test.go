@@ -0,0 +1,69 @@
+package tiles
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestKeysetIndexExpire(t *testing.T) {
+	idx := NewKeysetIndexExpiring(4)
+	esb := FromCoordinate(40.7484, -73.9857, 18)
+	sol := FromCoordinate(40.6892, -74.0445, 18)
+	bbn := FromCoordinate(51.5007, -0.1246, 18)
+	idx.Add(esb, "EmpireStateBuilding", "ChryslerBuilding")
+	idx.Upsert(sol, "ferry", "Ferry@SOL")
+	idx.Upsert(bbn, "plane", "Plane@BBN")
+	time.Sleep(time.Millisecond)
+	cutoff := time.Now()
+	time.Sleep(time.Millisecond)
+	idx.Add(sol, "StatueOfLiberty")
+	idx.Upsert(bbn, "plane", "Plane@BBN2")
+	if n := idx.Expire(cutoff); n != 3 {
+		t.Error("Expire expected to remove 3 values, got ", n)
+	}
+	exp := []interface{}{"Plane@BBN2", "StatueOfLiberty"}
+	if v := idx.Values(Tile{}); !reflect.DeepEqual(v, exp) {
+		t.Error("Expire world: ", v)
+	}
+	if _, ok := idx.Get(sol, "ferry"); ok {
+		t.Error("Expire kept the ferry id")
+	}
+	if v, ok := idx.Get(bbn, "plane"); !ok || v != "Plane@BBN2" {
+		t.Error("Expire plane: ", v, ok)
+	}
+	if n := idx.Expire(cutoff); n != 0 {
+		t.Error("Expire on an expired index removed ", n)
+	}
+	idx.Compact()
+	if n := idx.Expire(time.Now().Add(time.Second)); n != 2 || idx.Len() != 0 {
+		t.Error("Expire all removed ", n)
+	}
+}
+
+func TestKeysetIndexExpireCompactUpsert(t *testing.T) {
+	idx := NewKeysetIndexExpiring(4)
+	esb := FromCoordinate(40.7484, -73.9857, 18)
+	idx.Add(esb, "x")
+	time.Sleep(time.Millisecond)
+	cutoff := time.Now()
+	time.Sleep(time.Millisecond)
+	// the upserted key sits between the plain keys, so the merged key must take y's stamp itself
+	idx.Upsert(esb, "u", "u")
+	idx.Add(esb, "y")
+	idx.Compact()
+	if n := idx.Expire(cutoff); n != 0 {
+		t.Error("Expire after Compact removed values written after the cutoff: ", n, idx.Values(esb))
+	}
+	if v := idx.Values(esb); len(v) != 3 {
+		t.Error("Expire after Compact values: ", v)
+	}
+}
+
+func TestKeysetIndexExpireUnstamped(t *testing.T) {
+	idx := NewKeysetIndex(1)
+	idx.Add(Tile{}, "World")
+	if n := idx.Expire(time.Now().Add(time.Second)); n != 0 || idx.Len() != 1 {
+		t.Error("Expire on an unstamped index removed ", n)
+	}
+}
@@ -0,0 +1,83 @@
+package tiles
+
+import (
+	"errors"
+	"strings"
+)
+
+// base32 alphabet used by geohash, which skips a, i, l and o
+const geohashAlphabet = "0123456789bcdefghjkmnpqrstuvwxyz"
+
+// GeohashToTile returns the tile at zoom containing the center of the geohash cell.
+// The conversion is lossy, geohash cells and tiles don't line up so the tile may not cover the whole cell or may cover more than it.
+// Panics if hash has a character outside of the geohash alphabet
+func GeohashToTile(hash string, zoom int) Tile {
+	lat, lon, err := geohashCenter(hash)
+	check(err)
+	return FromCoordinate(lat, lon, zoom)
+}
+
+// Geohash returns the geohash of the tile's Center with precision characters.
+// Like GeohashToTile it's lossy, the geohash cell contains the tile's center but not necessarily the rest of the tile.
+// A negative precision is treated as 0 and returns ""
+func (t Tile) Geohash(precision int) string {
+	if precision < 0 {
+		precision = 0
+	}
+	lat, lon := t.Center()
+	latRange, lonRange := [2]float64{-90, 90}, [2]float64{-180, 180}
+	hash := make([]byte, 0, precision)
+	// bits alternate starting with longitude and are packed 5 to a character
+	even, bits, c := true, 0, 0
+	for len(hash) < precision {
+		c <<= 1
+		if even {
+			c |= bisect(&lonRange, lon)
+		} else {
+			c |= bisect(&latRange, lat)
+		}
+		even = !even
+		if bits++; bits == 5 {
+			hash = append(hash, geohashAlphabet[c])
+			bits, c = 0, 0
+		}
+	}
+	return string(hash)
+}
+
+// bisect halves the range to the side containing val, returning 1 if it's the upper half
+func bisect(r *[2]float64, val float64) int {
+	mid := (r[0] + r[1]) / 2
+	if val >= mid {
+		r[0] = mid
+		return 1
+	}
+	r[1] = mid
+	return 0
+}
+
+// geohashCenter returns the center of the geohash cell
+func geohashCenter(hash string) (lat, lon float64, err error) {
+	latRange, lonRange := [2]float64{-90, 90}, [2]float64{-180, 180}
+	even := true
+	for _, ch := range hash {
+		c := strings.IndexRune(geohashAlphabet, ch)
+		if c < 0 {
+			return 0, 0, errors.New("Invalid Geohash " + hash)
+		}
+		for mask := 16; mask > 0; mask >>= 1 {
+			r := &latRange
+			if even {
+				r = &lonRange
+			}
+			mid := (r[0] + r[1]) / 2
+			if c&mask != 0 {
+				r[0] = mid
+			} else {
+				r[1] = mid
+			}
+			even = !even
+		}
+	}
+	return (latRange[0] + latRange[1]) / 2, (lonRange[0] + lonRange[1]) / 2, nil
+}
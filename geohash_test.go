@@ -0,0 +1,56 @@
+package tiles
+
+import (
+	"testing"
+)
+
+func TestTileGeohash(t *testing.T) {
+	tests := []struct {
+		tile      Tile
+		precision int
+		hash      string
+	}{
+		{FromCoordinate(40.7484, -73.9857, 18), 7, "dr5ru6j"},
+		{FromCoordinate(51.5007, -0.1246, 18), 6, "gcpuvp"},
+		{FromCoordinate(40.7484, -73.9857, 18), 0, ""},
+		{FromCoordinate(40.7484, -73.9857, 18), -1, ""},
+		{Tile{X: 0, Y: 0, Z: 0}, 1, "s"},
+	}
+	errf := "%v.Geohash(%d) -> %q"
+	for _, test := range tests {
+		if hash := test.tile.Geohash(test.precision); hash != test.hash {
+			t.Errorf(errf, test.tile, test.precision, hash)
+		}
+	}
+}
+
+func TestGeohashToTile(t *testing.T) {
+	tests := []struct {
+		hash string
+		zoom int
+		tile Tile
+	}{
+		{"dr5ru6j", 14, FromCoordinate(40.7484, -73.9857, 14)},
+		{"gcpuvp", 10, FromCoordinate(51.5007, -0.1246, 10)},
+		{"", 1, Tile{X: 1, Y: 1, Z: 1}},
+		{"s", 1, Tile{X: 1, Y: 0, Z: 1}},
+	}
+	errf := "GeohashToTile(%q, %d) -> %v"
+	for _, test := range tests {
+		if tile := GeohashToTile(test.hash, test.zoom); tile != test.tile {
+			t.Errorf(errf, test.hash, test.zoom, tile)
+		}
+	}
+	// a tile's center round trips through a geohash precise enough to fall inside the tile
+	for _, tile := range []Tile{FromCoordinate(40.7484, -73.9857, 18), {X: 26, Y: 48, Z: 7}} {
+		if rt := GeohashToTile(tile.Geohash(12), tile.Z); rt != tile {
+			t.Errorf("GeohashToTile(%v.Geohash(12)) -> %v", tile, rt)
+		}
+	}
+	defer func() {
+		if recover() == nil {
+			t.Errorf("GeohashToTile(%q) did not panic", "dr5a")
+		}
+	}()
+	GeohashToTile("dr5a", 10)
+}
@@ -0,0 +1,111 @@
+package tiles
+
+import (
+	"encoding/json"
+	"errors"
+	"strconv"
+)
+
+// geoJSONFeature is a GeoJSON Feature with a Polygon geometry
+type geoJSONFeature struct {
+	Type       string            `json:"type"`
+	Geometry   geoJSONPolygon    `json:"geometry"`
+	Properties geoJSONProperties `json:"properties"`
+}
+
+type geoJSONPolygon struct {
+	Type        string         `json:"type"`
+	Coordinates [][][2]float64 `json:"coordinates"`
+}
+
+type geoJSONProperties struct {
+	Quadkey Quadkey `json:"quadkey"`
+	Tile    string  `json:"tile"`
+	X       int     `json:"x"`
+	Y       int     `json:"y"`
+	Z       int     `json:"z"`
+}
+
+type geoJSONFeatureCollection struct {
+	Type     string           `json:"type"`
+	Features []geoJSONFeature `json:"features"`
+}
+
+// feature returns the tile as a GeoJSON Feature.
+// The ring is the tile's Bounds counter-clockwise from the south west corner with {lon, lat} positions as RFC 7946 specifies
+func (t Tile) feature() geoJSONFeature {
+	minLat, minLon, maxLat, maxLon := t.Bounds()
+	ring := [][2]float64{
+		{minLon, minLat},
+		{maxLon, minLat},
+		{maxLon, maxLat},
+		{minLon, maxLat},
+		{minLon, minLat},
+	}
+	return geoJSONFeature{
+		Type:     "Feature",
+		Geometry: geoJSONPolygon{Type: "Polygon", Coordinates: [][][2]float64{ring}},
+		Properties: geoJSONProperties{
+			Quadkey: t.Quadkey(),
+			Tile:    t.String(),
+			X:       t.X,
+			Y:       t.Y,
+			Z:       t.Z,
+		},
+	}
+}
+
+// GeoJSON returns the tile as a GeoJSON Feature with a Polygon of its Bounds.
+// The properties hold the tile's quadkey, its z/x/y string and each of x, y and z
+func (t Tile) GeoJSON() ([]byte, error) {
+	return json.Marshal(t.feature())
+}
+
+// FeatureCollection returns a GeoJSON FeatureCollection with a Feature for each of the tiles in order, as Tile.GeoJSON encodes them.
+// No tiles encodes a collection with an empty features array
+func FeatureCollection(tiles []Tile) ([]byte, error) {
+	fc := geoJSONFeatureCollection{Type: "FeatureCollection", Features: make([]geoJSONFeature, len(tiles))}
+	for i, t := range tiles {
+		fc.Features[i] = t.feature()
+	}
+	return json.Marshal(fc)
+}
+
+// geoJSONPointCollection is the subset of a FeatureCollection of Points that AddGeoJSON reads
+type geoJSONPointCollection struct {
+	Type     string `json:"type"`
+	Features []struct {
+		Type     string `json:"type"`
+		Geometry *struct {
+			Type        string    `json:"type"`
+			Coordinates []float64 `json:"coordinates"`
+		} `json:"geometry"`
+		Properties map[string]interface{} `json:"properties"`
+	} `json:"features"`
+}
+
+// AddGeoJSON adds each Point feature in a GeoJSON FeatureCollection to the index at the tile containing it at zoom,
+// with the feature's properties as the value. Points are {lon, lat} and longitude is wrapped as in TileFromLatLng.
+// Returns the number of features added. Returns an error if data isn't a FeatureCollection or any feature isn't a Point,
+// in which case nothing is added
+func (idx *KeysetIndex) AddGeoJSON(data []byte, zoom int) (int, error) {
+	if zoom < 0 || zoom > ZMax {
+		return 0, errors.New("Invalid zoom " + strconv.Itoa(zoom))
+	}
+	var fc geoJSONPointCollection
+	if err := json.Unmarshal(data, &fc); err != nil {
+		return 0, err
+	}
+	if fc.Type != "FeatureCollection" {
+		return 0, errors.New("Invalid GeoJSON type " + strconv.Quote(fc.Type))
+	}
+	entries := make([]Entry, len(fc.Features))
+	for i, f := range fc.Features {
+		if f.Type != "Feature" || f.Geometry == nil || f.Geometry.Type != "Point" || len(f.Geometry.Coordinates) < 2 {
+			return 0, errors.New("Invalid GeoJSON Point feature " + strconv.Itoa(i))
+		}
+		lon, lat := f.Geometry.Coordinates[0], f.Geometry.Coordinates[1]
+		entries[i] = Entry{Tile: TileFromLatLng(lat, lon, zoom), Value: f.Properties}
+	}
+	return idx.AddBatch(entries), nil
+}
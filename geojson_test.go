@@ -0,0 +1,105 @@
+package tiles
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestTileGeoJSON(t *testing.T) {
+	tests := []struct {
+		tile Tile
+		json string
+	}{
+		{
+			Tile{X: 1, Y: 0, Z: 1},
+			`{"type":"Feature","geometry":{"type":"Polygon","coordinates":[[[0,0],[180,0],[180,85.05112877980659],[0,85.05112877980659],[0,0]]]},"properties":{"quadkey":"1","tile":"1/1/0","x":1,"y":0,"z":1}}`,
+		},
+		{
+			Tile{X: 26, Y: 48, Z: 7},
+			`{"type":"Feature","geometry":{"type":"Polygon","coordinates":[[[-106.875,38.8225909761771],[-104.0625,38.8225909761771],[-104.0625,40.979898069620134],[-106.875,40.979898069620134],[-106.875,38.8225909761771]]]},"properties":{"quadkey":"0231010","tile":"7/26/48","x":26,"y":48,"z":7}}`,
+		},
+	}
+	errf := "%v.GeoJSON() -> %s, %v"
+	for _, test := range tests {
+		data, err := test.tile.GeoJSON()
+		if err != nil || string(data) != test.json {
+			t.Errorf(errf, test.tile, data, err)
+		}
+	}
+}
+
+func TestFeatureCollection(t *testing.T) {
+	tests := []struct {
+		tiles []Tile
+		count int
+	}{
+		{nil, 0},
+		{[]Tile{{}}, 1},
+		{TilesForBBox(40.6, -74.1, 40.8, -73.9, 10), 2},
+	}
+	errf := "FeatureCollection(%v) -> %s, %v"
+	for _, test := range tests {
+		data, err := FeatureCollection(test.tiles)
+		var fc struct {
+			Type     string
+			Features []struct {
+				Type       string
+				Properties struct{ Tile string }
+			}
+		}
+		if err == nil {
+			err = json.Unmarshal(data, &fc)
+		}
+		if err != nil || fc.Type != "FeatureCollection" || fc.Features == nil || len(fc.Features) != test.count {
+			t.Errorf(errf, test.tiles, data, err)
+			continue
+		}
+		for i, f := range fc.Features {
+			if f.Type != "Feature" || f.Properties.Tile != test.tiles[i].String() {
+				t.Errorf(errf, test.tiles, data, err)
+			}
+		}
+	}
+}
+
+func TestKeysetIndexAddGeoJSON(t *testing.T) {
+	points := `{"type": "FeatureCollection", "features": [
+		{"type": "Feature", "geometry": {"type": "Point", "coordinates": [-73.9857, 40.7484]}, "properties": {"name": "ESB"}},
+		{"type": "Feature", "geometry": {"type": "Point", "coordinates": [-74.0445, 40.6892, 93]}, "properties": {"name": "SOL"}},
+		{"type": "Feature", "geometry": {"type": "Point", "coordinates": [-0.1246, 51.5007]}, "properties": null}
+	]}`
+	tests := []struct {
+		data  string
+		zoom  int
+		added int
+		err   bool
+	}{
+		{points, 18, 3, false},
+		{points, 8, 3, false},
+		{`{"type": "FeatureCollection", "features": []}`, 18, 0, false},
+		{points, ZMax + 1, 0, true},
+		{points, -1, 0, true},
+		{`{"type": "Feature"}`, 18, 0, true},
+		{`{"type": "FeatureCollection", "features": [{"type": "Feature", "geometry": {"type": "LineString", "coordinates": [[0, 0], [1, 1]]}}]}`, 18, 0, true},
+		{`{"type": "FeatureCollection", "features": [{"type": "Feature", "geometry": {"type": "Point", "coordinates": [0]}}]}`, 18, 0, true},
+		{`{"type": "FeatureCollection", "features": [{"type": "Feature", "geometry": null}]}`, 18, 0, true},
+		{`{"type": "FeatureCollection", "features": [`, 18, 0, true},
+	}
+	errf := "AddGeoJSON(%s, %d) -> %d, %v"
+	for _, test := range tests {
+		idx := NewKeysetIndex(0)
+		n, err := idx.AddGeoJSON([]byte(test.data), test.zoom)
+		if n != test.added || (err != nil) != test.err || idx.Len() != test.added {
+			t.Errorf(errf, test.data, test.zoom, n, err)
+		}
+	}
+	idx := NewKeysetIndex(0)
+	idx.AddGeoJSON([]byte(points), 18)
+	esb := FromCoordinate(40.7484, -73.9857, 18)
+	if vals := idx.Values(esb); len(vals) != 1 || vals[0].(map[string]interface{})["name"] != "ESB" {
+		t.Errorf("AddGeoJSON %v -> %v", esb, vals)
+	}
+	if vals := idx.Values(FromCoordinate(51.5007, -0.1246, 18)); len(vals) != 1 || vals[0].(map[string]interface{}) != nil {
+		t.Errorf("AddGeoJSON null properties -> %v", vals)
+	}
+}
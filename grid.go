@@ -0,0 +1,238 @@
+package tiles
+
+import (
+	"fmt"
+	"math"
+)
+
+// TileGrid is the pixel grid of the tile pyramid for a tile size, such as 256 for raster tiles or 512 for vector and retina tiles.
+// The package level helpers use a TileGrid of the current TileSize that wraps longitude, so a TileGrid is only needed to work in a different size
+// or to turn wrapping off. The zero value is a grid of the package level TileSize that doesn't wrap longitude
+type TileGrid struct {
+	size int
+	// WrapLongitude sets whether x wraps modulo 2^z across the antimeridian in Neighbor, Neighbors, TilesForBBox and TilesForLine.
+	// When it's off the map ends at ±180, so neighbors past it are dropped, a bbox with minLon > maxLon is empty
+	// and a line is walked straight across the map rather than over the antimeridian.
+	// NewTileGrid turns it on, so a grid that doesn't wrap is NewTileGrid's with WrapLongitude set to false, or TileGrid{} in the package level TileSize
+	WrapLongitude bool
+}
+
+// NewTileGrid returns the TileGrid for tiles tileSize pixels wide that wraps longitude like the package level helpers.
+// Panics if tileSize isn't positive
+func NewTileGrid(tileSize int) TileGrid {
+	if tileSize <= 0 {
+		panic(fmt.Errorf("tile size %d <= 0", tileSize))
+	}
+	return TileGrid{size: tileSize, WrapLongitude: true}
+}
+
+// grid returns the TileGrid for the package level TileSize
+func grid() TileGrid {
+	return TileGrid{size: TileSize, WrapLongitude: true}
+}
+
+// TileSize returns the width of a tile in pixels, which is the package level TileSize for the zero value
+func (g TileGrid) TileSize() int {
+	if g.size == 0 {
+		return TileSize
+	}
+	return g.size
+}
+
+// Gets the size of the x, y dimensions in pixels at the given zoom level
+func (g TileGrid) mapDimensions(zoom int) int {
+	//TODO panic outside of zoom bounds
+	return g.TileSize() << uint(zoom)
+}
+
+// Gets the ground resoultion (meters/pixel) of the map at the lat and zoom
+func (g TileGrid) grndRes(lat float64, zoom int) float64 {
+	lat = clip(lat, MinLat, MaxLat)
+	dim := float64(g.mapDimensions(zoom))
+	return math.Cos(lat*math.Pi/180) * 2 * math.Pi * EarthRadiusM / dim
+}
+
+// ResolutionMeters returns the ground resolution in meters per pixel at the tile's center
+func (g TileGrid) ResolutionMeters(t Tile) float64 {
+	lat, _ := t.Center()
+	return g.grndRes(lat, t.Z)
+}
+
+// ZoomForResolution returns the lowest zoom whose ground resolution at lat is at most meters per pixel.
+// The zoom is clipped to [0, ZMax]
+func (g TileGrid) ZoomForResolution(lat, meters float64) int {
+	if meters <= 0 {
+		return ZMax
+	}
+	// a hair of slack so resolutions that are exactly a zoom's don't round up to the next one
+	z := int(math.Ceil(math.Log2(g.grndRes(lat, 0)/meters) - 1e-9))
+	return int(clip(float64(z), 0, ZMax))
+}
+
+// TileToPixel returns the NW pixel of the tile
+func (g TileGrid) TileToPixel(t Tile) Pixel {
+	size := g.TileSize()
+	return Pixel{
+		X: t.X * size,
+		Y: t.Y * size,
+		Z: t.Z,
+	}
+}
+
+// PixelToTile gets the tile that contains the pixel as well as the offset pixel within that tile
+func (g TileGrid) PixelToTile(p Pixel) (tile Tile, offset TilePixel) {
+	size := g.TileSize()
+	tile = Tile{
+		X: p.X / size,
+		Y: p.Y / size,
+		Z: p.Z,
+	}
+	offset = TilePixel{
+		X:    p.X % size,
+		Y:    p.Y % size,
+		Tile: &tile,
+	}
+	return
+}
+
+// SubTileAtPixel returns the descendant of the tile deltaZoom levels down that contains the pixel px, py within the tile.
+// Pixels outside the tile, including px or py equal to the tile size, are clamped to its edges.
+// deltaZoom is clipped so the descendant is no deeper than ZMax, and t is returned if it isn't positive
+func (g TileGrid) SubTileAtPixel(t Tile, px, py, deltaZoom int) Tile {
+	if deltaZoom > ZMax-t.Z {
+		deltaZoom = ZMax - t.Z
+	}
+	if deltaZoom <= 0 {
+		return t
+	}
+	size := g.TileSize()
+	last := float64(size - 1)
+	px, py = int(clip(float64(px), 0, last)), int(clip(float64(py), 0, last))
+	d := uint(deltaZoom)
+	return Tile{
+		X: t.X<<d + px<<d/size,
+		Y: t.Y<<d + py<<d/size,
+		Z: t.Z + deltaZoom,
+	}
+}
+
+// CoordinateToPixel gets the Pixel of the coord at the zoom level
+func (g TileGrid) CoordinateToPixel(c Coordinate, zoom int) Pixel {
+	x := (c.Lon + 180) / 360.0
+	sinLat := math.Sin(c.Lat * math.Pi / 180.0)
+	y := 0.5 - math.Log((1+sinLat)/(1-sinLat))/(4*math.Pi)
+	size := float64(g.mapDimensions(zoom))
+	return Pixel{
+		X: int(clip(x*size+0.5, 0, size-1)),
+		Y: int(clip(y*size+0.5, 0, size-1)),
+		Z: zoom,
+	}
+}
+
+// PixelToCoordinate converts the pixel to WGS84 coordinates
+func (g TileGrid) PixelToCoordinate(p Pixel) Coordinate {
+	size := float64(g.mapDimensions(p.Z))
+	x := (clip(p.floatX(), 0, size-1) / size) - 0.5
+	y := 0.5 - (clip(p.floatY(), 0, size-1) / size)
+	lat := 90 - 360*math.Atan(math.Exp(-y*2*math.Pi))/math.Pi
+	lon := 360.0 * x
+	return ClippedCoords(lat, lon)
+}
+
+// Neighbor returns the tile offset by dx, dy at the same zoom.
+// There's nothing past the poles so ok is false if y is out of range, and the same goes for x unless the grid wraps longitude
+func (g TileGrid) Neighbor(t Tile, dx, dy int) (tile Tile, ok bool) {
+	n := 1 << uint(t.Z)
+	x, y := t.X+dx, t.Y+dy
+	if y < 0 || y >= n {
+		return
+	}
+	if !g.WrapLongitude && (x < 0 || x >= n) {
+		return
+	}
+	x %= n
+	if x < 0 {
+		x += n
+	}
+	return Tile{X: x, Y: y, Z: t.Z}, true
+}
+
+// Neighbors returns the adjacent tiles at the same zoom in N, NE, E, SE, S, SW, W, NW order, leaving out those Neighbor drops.
+// At shallow zooms where wrapping makes neighbors coincide, each tile is returned once and t itself is omitted
+func (g TileGrid) Neighbors(t Tile) (tiles []Tile) {
+	offsets := [8][2]int{{0, -1}, {1, -1}, {1, 0}, {1, 1}, {0, 1}, {-1, 1}, {-1, 0}, {-1, -1}}
+	seen := map[Tile]struct{}{t: {}}
+	for _, o := range offsets {
+		n, ok := g.Neighbor(t, o[0], o[1])
+		if _, dup := seen[n]; !ok || dup {
+			continue
+		}
+		seen[n] = struct{}{}
+		tiles = append(tiles, n)
+	}
+	return
+}
+
+// TilesForBBox returns the tiles at zoom that intersect the bbox in row-major order, north to south then west to east.
+// If minLon > maxLon the bbox crosses the antimeridian. When the grid wraps longitude the x range wraps around it,
+// so the tiles east of the antimeridian follow those west of it in each row, otherwise there are no tiles
+func (g TileGrid) TilesForBBox(minLat, minLon, maxLat, maxLon float64, zoom int) (tiles []Tile) {
+	if minLon > maxLon && !g.WrapLongitude {
+		return
+	}
+	// the span runs from the tile holding the north west corner to the last tile the box reaches into,
+	// so a box ending on a tile's edge doesn't take in the tile past it
+	n := float64(uint(1) << uint(zoom))
+	x0, y0 := tileSpace(maxLat, minLon, n)
+	x1, y1 := tileSpace(minLat, maxLon, n)
+	xlo, ylo := int(clip(math.Floor(x0), 0, n-1)), int(clip(math.Floor(y0), 0, n-1))
+	xhi, yhi := lastCell(x0, x1, n), lastCell(y0, y1, n)
+	if minLon > maxLon {
+		// the east part of a wrapped box starts at the antimeridian
+		xhi = lastCell(0, x1, n)
+	}
+	xs := spanX(xlo, xhi, zoom, minLon > maxLon)
+	for y := ylo; y <= yhi; y++ {
+		for _, x := range xs {
+			tiles = append(tiles, Tile{X: x, Y: y, Z: zoom})
+		}
+	}
+	return
+}
+
+// TilesForLine returns the tiles at zoom that the polyline passes through in the order the line reaches them, each tile only once.
+// Each point is a {lat, lon} pair. Segments are walked cell by cell as straight lines on the mercator map, rhumb lines rather than great circles.
+// When the grid wraps longitude, a segment whose ends are more than 180 degrees of longitude apart is taken to cross the antimeridian and split there
+func (g TileGrid) TilesForLine(points [][2]float64, zoom int) (tiles []Tile) {
+	n := float64(uint(1) << uint(zoom))
+	seen := make(map[Tile]struct{})
+	visit := func(x, y int) {
+		t := Tile{X: x, Y: y, Z: zoom}
+		if _, ok := seen[t]; !ok {
+			seen[t] = struct{}{}
+			tiles = append(tiles, t)
+		}
+	}
+	for i, p := range points {
+		x1, y1 := tileSpace(p[0], p[1], n)
+		if i == 0 {
+			walkSegment(x1, y1, x1, y1, n, visit)
+			continue
+		}
+		x0, y0 := tileSpace(points[i-1][0], points[i-1][1], n)
+		switch {
+		case g.WrapLongitude && x1-x0 > n/2:
+			// heading west over the antimeridian, x0 runs down to 0 and picks up from n
+			yc := y0 + (y1-y0)*x0/(x0+n-x1)
+			walkSegment(x0, y0, 0, yc, n, visit)
+			walkSegment(n, yc, x1, y1, n, visit)
+		case g.WrapLongitude && x0-x1 > n/2:
+			yc := y0 + (y1-y0)*(n-x0)/(x1+n-x0)
+			walkSegment(x0, y0, n, yc, n, visit)
+			walkSegment(0, yc, x1, y1, n, visit)
+		default:
+			walkSegment(x0, y0, x1, y1, n, visit)
+		}
+	}
+	return
+}
@@ -0,0 +1,162 @@
+package tiles
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestTileGridResolution(t *testing.T) {
+	den := Tile{X: 26, Y: 48, Z: 7}
+	tests := []struct {
+		size int
+		tile Tile
+		res  float64
+		zoom int
+	}{
+		{256, den, 938.22015, 8},
+		{512, den, 469.11007, 7},
+		{1024, den, 234.55503, 6},
+	}
+	errf := "NewTileGrid(%d).%s -> %v"
+	for _, test := range tests {
+		g := NewTileGrid(test.size)
+		if g.TileSize() != test.size {
+			t.Errorf(errf, test.size, "TileSize()", g.TileSize())
+		}
+		if res := g.ResolutionMeters(test.tile); math.Abs(res-test.res) > 1e-5 {
+			t.Errorf(errf, test.size, "ResolutionMeters", res)
+		}
+		if zoom := g.ZoomForResolution(40, 900); zoom != test.zoom {
+			t.Errorf(errf, test.size, "ZoomForResolution", zoom)
+		}
+	}
+	if res, def := NewTileGrid(TileSize).ResolutionMeters(den), den.ResolutionMeters(); res != def {
+		t.Errorf("ResolutionMeters for the default grid %v != %v", res, def)
+	}
+}
+
+func TestTileGridPixels(t *testing.T) {
+	esb := ClippedCoords(40.7484, -73.9857)
+	tests := []struct {
+		size  int
+		pixel Pixel
+		tile  Tile
+		x, y  int
+	}{
+		{256, Pixel{X: 19762498, Y: 25222887, Z: 18}, Tile{X: 77197, Y: 98526, Z: 18}, 66, 231},
+		{512, Pixel{X: 39524996, Y: 50445773, Z: 18}, Tile{X: 77197, Y: 98526, Z: 18}, 132, 461},
+		{512, Pixel{X: 19762498, Y: 25222887, Z: 17}, Tile{X: 38598, Y: 49263, Z: 17}, 322, 231},
+	}
+	errf := "NewTileGrid(%d).%s -> %v"
+	for _, test := range tests {
+		g := NewTileGrid(test.size)
+		if p := g.CoordinateToPixel(esb, test.pixel.Z); p != test.pixel {
+			t.Errorf(errf, test.size, "CoordinateToPixel", p)
+		}
+		tile, offset := g.PixelToTile(test.pixel)
+		if tile != test.tile || offset.X != test.x || offset.Y != test.y || *offset.Tile != tile {
+			t.Errorf(errf, test.size, "PixelToTile", tile)
+		}
+		if p := g.TileToPixel(tile); p.X != test.pixel.X-test.x || p.Y != test.pixel.Y-test.y || p.Z != tile.Z {
+			t.Errorf(errf, test.size, "TileToPixel", p)
+		}
+		if c := g.PixelToCoordinate(test.pixel); math.Abs(c.Lat-esb.Lat) > 1e-3 || math.Abs(c.Lon-esb.Lon) > 1e-3 {
+			t.Errorf(errf, test.size, "PixelToCoordinate", c)
+		}
+	}
+}
+
+func TestNewTileGridPanics(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Errorf("NewTileGrid(0) did not panic")
+		}
+	}()
+	NewTileGrid(0)
+}
+
+func TestTileGridZeroValue(t *testing.T) {
+	var zero TileGrid
+	def := NewTileGrid(TileSize)
+	den := Tile{X: 26, Y: 48, Z: 7}
+	if zero.TileSize() != TileSize {
+		t.Error("TileGrid{}.TileSize() -> ", zero.TileSize())
+	}
+	if res := zero.ResolutionMeters(den); res != def.ResolutionMeters(den) {
+		t.Error("TileGrid{}.ResolutionMeters -> ", res)
+	}
+	p := Pixel{X: 19762498, Y: 25222887, Z: 18}
+	if tile, offset := zero.PixelToTile(p); tile != (Tile{X: 77197, Y: 98526, Z: 18}) || offset.X != 66 || offset.Y != 231 {
+		t.Error("TileGrid{}.PixelToTile -> ", tile, offset.X, offset.Y)
+	}
+	if px := zero.TileToPixel(den); px != def.TileToPixel(den) {
+		t.Error("TileGrid{}.TileToPixel -> ", px)
+	}
+	if sub := zero.SubTileAtPixel(den, 255, 0, 1); sub != (Tile{X: 53, Y: 96, Z: 8}) {
+		t.Error("TileGrid{}.SubTileAtPixel -> ", sub)
+	}
+	if tiles := zero.TilesForBBox(-10, -170, 10, 170, 3); !reflect.DeepEqual(tiles, def.TilesForBBox(-10, -170, 10, 170, 3)) {
+		t.Error("TileGrid{}.TilesForBBox -> ", tiles)
+	}
+}
+
+func TestTileGridWrapLongitude(t *testing.T) {
+	wrap := NewTileGrid(256)
+	flat := NewTileGrid(256)
+	flat.WrapLongitude = false
+	// 170 to -170 crosses the antimeridian, so the wrapped columns run east off the map and back in from the west
+	for _, zoom := range []int{3, 6, 10} {
+		n := 1 << uint(zoom)
+		tiles := wrap.TilesForBBox(-10, 170, 10, -170, zoom)
+		if len(tiles) == 0 || !reflect.DeepEqual(tiles, TilesForBBox(-10, 170, 10, -170, zoom)) {
+			t.Errorf("wrapped TilesForBBox at %d -> %v", zoom, tiles)
+		}
+		for i := 1; i < len(tiles); i++ {
+			a, b := tiles[i-1], tiles[i]
+			if a.Y == b.Y && (a.X+1)%n != b.X {
+				t.Errorf("wrapped TilesForBBox at %d isn't contiguous: %v, %v", zoom, a, b)
+			}
+		}
+		if tiles := flat.TilesForBBox(-10, 170, 10, -170, zoom); tiles != nil {
+			t.Errorf("unwrapped TilesForBBox at %d -> %v", zoom, tiles)
+		}
+		if w, f := wrap.TilesForBBox(-10, -170, 10, 170, zoom), flat.TilesForBBox(-10, -170, 10, 170, zoom); !reflect.DeepEqual(w, f) {
+			t.Errorf("TilesForBBox without crossing at %d differs: %v != %v", zoom, w, f)
+		}
+	}
+	edge := Tile{X: 0, Y: 3, Z: 3}
+	neighborTests := []struct {
+		grid  TileGrid
+		tiles []Tile
+	}{
+		{wrap, []Tile{{X: 0, Y: 2, Z: 3}, {X: 1, Y: 2, Z: 3}, {X: 1, Y: 3, Z: 3}, {X: 1, Y: 4, Z: 3}, {X: 0, Y: 4, Z: 3}, {X: 7, Y: 4, Z: 3}, {X: 7, Y: 3, Z: 3}, {X: 7, Y: 2, Z: 3}}},
+		{flat, []Tile{{X: 0, Y: 2, Z: 3}, {X: 1, Y: 2, Z: 3}, {X: 1, Y: 3, Z: 3}, {X: 1, Y: 4, Z: 3}, {X: 0, Y: 4, Z: 3}}},
+		{TileGrid{WrapLongitude: false}, []Tile{{X: 0, Y: 2, Z: 3}, {X: 1, Y: 2, Z: 3}, {X: 1, Y: 3, Z: 3}, {X: 1, Y: 4, Z: 3}, {X: 0, Y: 4, Z: 3}}},
+	}
+	for _, test := range neighborTests {
+		if tiles := test.grid.Neighbors(edge); !reflect.DeepEqual(tiles, test.tiles) {
+			t.Errorf("Neighbors(%v) wrap %t -> %v", edge, test.grid.WrapLongitude, tiles)
+		}
+	}
+	if tiles := wrap.Neighbors(edge); !reflect.DeepEqual(tiles, edge.Neighbors()) {
+		t.Errorf("wrapped Neighbors(%v) -> %v != %v", edge, tiles, edge.Neighbors())
+	}
+	line := [][2]float64{{0, 170}, {0, -170}}
+	lineTests := []struct {
+		grid  TileGrid
+		tiles []Tile
+	}{
+		{wrap, []Tile{{X: 7, Y: 4, Z: 3}, {X: 0, Y: 4, Z: 3}}},
+		{flat, []Tile{{X: 7, Y: 4, Z: 3}, {X: 6, Y: 4, Z: 3}, {X: 5, Y: 4, Z: 3}, {X: 4, Y: 4, Z: 3}, {X: 3, Y: 4, Z: 3}, {X: 2, Y: 4, Z: 3}, {X: 1, Y: 4, Z: 3}, {X: 0, Y: 4, Z: 3}}},
+		{TileGrid{WrapLongitude: false}, []Tile{{X: 7, Y: 4, Z: 3}, {X: 6, Y: 4, Z: 3}, {X: 5, Y: 4, Z: 3}, {X: 4, Y: 4, Z: 3}, {X: 3, Y: 4, Z: 3}, {X: 2, Y: 4, Z: 3}, {X: 1, Y: 4, Z: 3}, {X: 0, Y: 4, Z: 3}}},
+	}
+	for _, test := range lineTests {
+		if tiles := test.grid.TilesForLine(line, 3); !reflect.DeepEqual(tiles, test.tiles) {
+			t.Errorf("TilesForLine(%v) wrap %t -> %v", line, test.grid.WrapLongitude, tiles)
+		}
+	}
+	if tiles := (TileGrid{WrapLongitude: false}).TilesForBBox(-10, 170, 10, -170, 3); tiles != nil {
+		t.Error("TileGrid{WrapLongitude: false}.TilesForBBox -> ", tiles)
+	}
+}
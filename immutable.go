@@ -0,0 +1,128 @@
+package tiles
+
+import (
+	"context"
+	"reflect"
+)
+
+// Builder collects values for an ImmutableIndex, separating a write-heavy load from a read-only serving index.
+// It uses a KeysetIndex internally, so it's thread safe
+type Builder struct {
+	idx KeysetIndex
+}
+
+// NewBuilder returns an empty Builder
+func NewBuilder() *Builder {
+	return &Builder{}
+}
+
+// Add adds values to the tile
+func (b *Builder) Add(t Tile, val ...interface{}) {
+	b.idx.Add(t, val...)
+}
+
+// AddBatch adds all of the entries under a single lock. Returns the number of entries added
+func (b *Builder) AddBatch(entries []Entry) int {
+	b.idx.Lock()
+	defer b.idx.Unlock()
+	b.idx.append(entries)
+	return len(entries)
+}
+
+// Build returns an ImmutableIndex of the values added so far, sorted with the values added to the same quadkey merged into one key in insertion order.
+// Values at the same quadkey that are equal using reflect.DeepEqual are kept once, as in Compact.
+// The index doesn't share storage with the builder, so the builder can keep adding for a later Build
+func (b *Builder) Build() *ImmutableIndex {
+	b.idx.rlockSorted()
+	defer b.idx.RUnlock()
+	im := &ImmutableIndex{}
+	im.idx.sorted = true
+	for i, k := range b.idx.keys {
+		if i == 0 || k.qk != b.idx.keys[i-1].qk {
+			im.idx.values = append(im.idx.values, nil)
+			im.idx.keys = append(im.idx.keys, qkey{qk: k.qk, v: len(im.idx.values) - 1})
+		}
+		last := len(im.idx.values) - 1
+	next:
+		for _, v := range b.idx.values[k.v] {
+			for _, o := range im.idx.values[last] {
+				if reflect.DeepEqual(v, o) {
+					continue next
+				}
+			}
+			im.idx.values[last] = append(im.idx.values[last], v)
+		}
+	}
+	return im
+}
+
+// ImmutableIndex is an index built by a Builder that can't be modified, so its queries don't take any locks.
+// It has no Add, so it isn't a TileIndex. ImmutableIndex is thread safe
+type ImmutableIndex struct {
+	// the keyset is sorted and never written after Build, so its helpers are used without its lock
+	idx KeysetIndex
+}
+
+// TileRange returns a channel of all tiles in the index in the zoom range. See KeysetIndex.TileRange
+func (im *ImmutableIndex) TileRange(zmin, zmax int) <-chan Tile {
+	return im.TileRangeContext(context.Background(), zmin, zmax)
+}
+
+// TileRangeContext is TileRange that stops sending and closes the channel when ctx is done.
+func (im *ImmutableIndex) TileRangeContext(ctx context.Context, zmin, zmax int) <-chan Tile {
+	tiles := make(chan Tile, 1<<10)
+	if zmax < zmin || zmax < 0 {
+		close(tiles)
+		return tiles
+	}
+	go func() {
+		defer close(tiles)
+		im.idx.tileRange(0, len(im.idx.keys), zmin, zmax, func(t Tile) bool {
+			select {
+			case tiles <- t:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		})
+	}()
+	return tiles
+}
+
+// Tiles returns the tiles TileRange would send, in the same order, computed synchronously
+func (im *ImmutableIndex) Tiles(zmin, zmax int) (tiles []Tile) {
+	if zmax < zmin || zmax < 0 {
+		return
+	}
+	im.idx.tileRange(0, len(im.idx.keys), zmin, zmax, func(t Tile) bool {
+		tiles = append(tiles, t)
+		return true
+	})
+	return
+}
+
+// Values returns a list of values aggregated under the requested tile in quadkey order and insertion order within a quadkey
+func (im *ImmutableIndex) Values(t Tile) (vals []interface{}) {
+	im.idx.scan(packTile(t), func(k qkey) bool {
+		vals = append(vals, im.idx.values[k.v]...)
+		return true
+	})
+	return
+}
+
+// Count returns the number of values aggregated under the requested tile without collecting them
+func (im *ImmutableIndex) Count(t Tile) (n int) {
+	im.idx.scan(packTile(t), func(k qkey) bool {
+		n += len(im.idx.values[k.v])
+		return true
+	})
+	return
+}
+
+// Len returns the number of values in the index
+func (im *ImmutableIndex) Len() (n int) {
+	for _, vals := range im.idx.values {
+		n += len(vals)
+	}
+	return
+}
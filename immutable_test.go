@@ -0,0 +1,64 @@
+package tiles
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestImmutableIndex(t *testing.T) {
+	b := NewBuilder()
+	esb := FromCoordinate(40.7484, -73.9857, 18)
+	sol := FromCoordinate(40.6892, -74.0445, 18)
+	bbn := FromCoordinate(51.5007, -0.1246, 18)
+	nyc := Tile{X: 75, Y: 96, Z: 8}
+	b.Add(sol, "StatueOfLiberty")
+	b.Add(esb, "EmpireStateBuilding")
+	b.AddBatch([]Entry{{bbn, "BigBen"}, {esb, "ChryslerBuilding"}})
+	b.Add(nyc, "NewYork")
+	b.Add(esb, "EmpireStateBuilding")
+	b.Add(nyc, landmark{"NewYork", 0}, landmark{"NewYork", 0})
+	idx := b.Build()
+	if n := len(idx.idx.keys); n != 4 {
+		t.Error("Build didn't merge equal quadkeys: ", n)
+	}
+	tests := []struct {
+		tile Tile
+		vals []interface{}
+	}{
+		{esb, []interface{}{"EmpireStateBuilding", "ChryslerBuilding"}},
+		{nyc, []interface{}{"NewYork", landmark{"NewYork", 0}, "EmpireStateBuilding", "ChryslerBuilding", "StatueOfLiberty"}},
+		{Tile{X: 106, Y: 194, Z: 9}, nil},
+	}
+	errf := "ImmutableIndex.Values(%v) -> %v"
+	for _, test := range tests {
+		if vals := idx.Values(test.tile); !reflect.DeepEqual(vals, test.vals) {
+			t.Errorf(errf, test.tile, vals)
+		}
+		if n := idx.Count(test.tile); n != len(test.vals) {
+			t.Errorf("ImmutableIndex.Count(%v) -> %d", test.tile, n)
+		}
+	}
+	// the same values in a KeysetIndex give the same tiles
+	ref := &KeysetIndex{}
+	ref.Add(sol, "StatueOfLiberty")
+	ref.Add(esb, "EmpireStateBuilding", "ChryslerBuilding")
+	ref.Add(bbn, "BigBen")
+	ref.Add(nyc, "NewYork", landmark{"NewYork", 0})
+	for _, zr := range [][2]int{{0, 18}, {8, 12}, {-1, 30}, {5, 2}} {
+		var tiles []Tile
+		for tile := range idx.TileRange(zr[0], zr[1]) {
+			tiles = append(tiles, tile)
+		}
+		if exp := ref.Tiles(zr[0], zr[1]); !reflect.DeepEqual(tiles, exp) || !reflect.DeepEqual(idx.Tiles(zr[0], zr[1]), exp) {
+			t.Errorf("ImmutableIndex.TileRange(%d, %d) -> %v", zr[0], zr[1], tiles)
+		}
+	}
+	// the builder keeps adding without changing what it already built
+	b.Add(esb, "Macy's")
+	if idx.Len() != 6 || b.Build().Len() != 7 {
+		t.Error("Build shares storage with the builder: ", idx.Len())
+	}
+	if NewBuilder().Build().Values(Tile{}) != nil {
+		t.Error("Empty ImmutableIndex has values")
+	}
+}
@@ -2,9 +2,14 @@ package tiles
 
 import (
 	"bytes"
+	"context"
+	"errors"
 	"index/suffixarray"
+	"io"
+	"reflect"
 	"sort"
 	"sync"
+	"unsafe"
 )
 
 const (
@@ -33,88 +38,1428 @@ type KeysetIndex struct {
 	sorted bool
 	keys   []qkey
 	values [][]interface{}
+	// ids maps the ids of upserted values to their keys, each of which has its own value slot
+	ids map[string]qkey
 	sync.RWMutex
+	// watchers registered with Watch have their own lock so events are sent without holding the index's
+	watchMu  sync.Mutex
+	watchers []chan<- Event
+	// capped is set by NewKeysetIndexMaxZoom, keys deeper than maxZoom are truncated to it
+	capped  bool
+	maxZoom int
+	// stamped is set by NewKeysetIndexExpiring, each key records when it was written for Expire
+	stamped bool
+	// wal is set by NewKeysetIndexWAL, writes are logged to it before they're applied
+	wal io.Writer
+	// leaves is set by NewKeysetIndexLeaves, writes that overlap a stored key at another depth are rejected
+	leaves bool
+}
+
+// NewKeysetIndex returns an empty KeysetIndex with room for capacity values before it needs to grow
+func NewKeysetIndex(capacity int) *KeysetIndex {
+	return &KeysetIndex{
+		sorted: true,
+		keys:   make([]qkey, 0, capacity),
+		values: make([][]interface{}, 0, capacity),
+	}
+}
+
+// NewKeysetIndexMaxZoom returns an empty KeysetIndex like NewKeysetIndex that stores values added deeper than maxZoom at their parent at maxZoom,
+// as if PruneBelow(maxZoom) ran after every write. It applies to Add, AddSorted, AddBatch, LoadFrom, Upsert, Merge, ReadFrom and UnmarshalJSON,
+// and Delete looks for the value at the truncated tile. maxZoom is clipped to [0, ZMax]
+func NewKeysetIndexMaxZoom(capacity, maxZoom int) *KeysetIndex {
+	idx := NewKeysetIndex(capacity)
+	idx.capped = true
+	idx.maxZoom = int(clip(float64(maxZoom), 0, ZMax))
+	return idx
+}
+
+// MaxZoom returns the zoom that keys are truncated to and true if the index was made by NewKeysetIndexMaxZoom
+func (idx *KeysetIndex) MaxZoom() (int, bool) {
+	return idx.maxZoom, idx.capped
+}
+
+// key packs the tile's quadkey for storing, truncated to maxZoom if the index is capped
+func (idx *KeysetIndex) key(t Tile) pkey {
+	return idx.truncate(packTile(t))
+}
+
+func (idx *KeysetIndex) truncate(qk pkey) pkey {
+	if idx.capped && qk.Level() > idx.maxZoom {
+		return qk.Parent(idx.maxZoom)
+	}
+	return qk
 }
 
 // TileRange returns a channel of all tiles in the index in the zoom range
-// If zmax is greater than the deepest tile level, the deepest tile level returns
+// Each tile is emitted once, after all of the tiles under it.
+// If zmax is greater than the deepest tile level, the deepest tile level returns.
+// No key has tiles deeper than its own level, so the tiles are the same as with zmax clamped to the deepest level in the index
+// A negative zmin is treated as 0 and if zmax < zmin the channel is returned closed without locking the index
 // Acquires a readlock for duration of returned channel being open
 func (idx *KeysetIndex) TileRange(zmin, zmax int) <-chan Tile {
-	idx.sort()
-	tiles := make(chan Tile, 1<<10)
+	return idx.TileRangeContext(context.Background(), zmin, zmax)
+}
+
+// TileRangeBuffered is TileRange with a channel buffer of the given size, 0 for unbuffered.
+// TileRange buffers 1<<10 tiles; a smaller buffer keeps the producer closer to a slow consumer,
+// a larger one lets a fast consumer drain the index with fewer handoffs
+func (idx *KeysetIndex) TileRangeBuffered(zmin, zmax, buffer int) <-chan Tile {
+	return idx.tileRangeChan(context.Background(), 0, zmin, zmax, buffer)
+}
+
+// TileRangeContext is TileRange that stops sending and closes the channel when ctx is done.
+// Cancel ctx when abandoning the channel early so the readlock is released.
+func (idx *KeysetIndex) TileRangeContext(ctx context.Context, zmin, zmax int) <-chan Tile {
+	return idx.tileRangeChan(ctx, 0, zmin, zmax, 1<<10)
+}
+
+// tileRangeChan sends the tiles from the keys under root, the zoom 0 key for the whole keyset, until ctx is done
+func (idx *KeysetIndex) tileRangeChan(ctx context.Context, root pkey, zmin, zmax, buffer int) <-chan Tile {
+	if buffer < 0 {
+		buffer = 0
+	}
+	tiles := make(chan Tile, buffer)
+	if zmax < zmin || zmax < 0 {
+		close(tiles)
+		return tiles
+	}
 	go func() {
 		defer close(tiles)
-		idx.RLock()
+		idx.rlockSorted()
+		defer idx.RUnlock()
+		idx.tileRange(idx.search(root), idx.upper(root), zmin, zmax, func(t Tile) bool {
+			select {
+			case tiles <- t:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		})
+	}()
+	return tiles
+}
+
+// Tiles returns the tiles TileRange would send, in the same order, computed synchronously under a single readlock.
+// There's no goroutine or channel and the lock is released before it returns, so it suits batch jobs that want every tile anyway
+func (idx *KeysetIndex) Tiles(zmin, zmax int) (tiles []Tile) {
+	if zmax < zmin || zmax < 0 {
+		return
+	}
+	idx.rlockSorted()
+	defer idx.RUnlock()
+	idx.tileRange(0, len(idx.keys), zmin, zmax, func(t Tile) bool {
+		tiles = append(tiles, t)
+		return true
+	})
+	return
+}
+
+// TileRangeUnder is TileRange restricted to root and the tiles under it, for refreshing one region without walking the whole index.
+// Only the keys with root's quadkey as a prefix are walked, found by binary search, and zmin is raised to root's zoom so none of root's ancestors are sent.
+// Acquires a readlock for duration of returned channel being open
+func (idx *KeysetIndex) TileRangeUnder(root Tile, zmin, zmax int) <-chan Tile {
+	return idx.TileRangeUnderContext(context.Background(), root, zmin, zmax)
+}
+
+// TileRangeUnderContext is TileRangeUnder that stops sending and closes the channel when ctx is done.
+// Cancel ctx when abandoning the channel early so the readlock is released.
+func (idx *KeysetIndex) TileRangeUnderContext(ctx context.Context, root Tile, zmin, zmax int) <-chan Tile {
+	if zmin < root.Z {
+		zmin = root.Z
+	}
+	return idx.tileRangeChan(ctx, packTile(root), zmin, zmax, 1<<10)
+}
+
+// TileCount is a tile emitted by TileRangeCounts with the number of values under it
+type TileCount struct {
+	Tile  Tile
+	Count int
+}
+
+// TileRangeCounts is TileRange with the number of values aggregated under each tile, the Count of the tile, in the same order.
+// A running count is kept per zoom as the keys are walked and sent with the tile at the last key under it, so each tile is counted in the same single pass.
+// Acquires a readlock for duration of returned channel being open
+func (idx *KeysetIndex) TileRangeCounts(zmin, zmax int) <-chan TileCount {
+	return idx.TileRangeCountsContext(context.Background(), zmin, zmax)
+}
+
+// TileRangeCountsContext is TileRangeCounts that stops sending and closes the channel when ctx is done.
+// Cancel ctx when abandoning the channel early so the readlock is released.
+func (idx *KeysetIndex) TileRangeCountsContext(ctx context.Context, zmin, zmax int) <-chan TileCount {
+	return idx.tileRangeCounts(ctx, zmin, zmax, func(vals []interface{}) int {
+		return len(vals)
+	})
+}
+
+// tileRangeCounts is TileRangeCountsContext with each key counted as count(vals) of its values
+func (idx *KeysetIndex) tileRangeCounts(ctx context.Context, zmin, zmax int, count func(vals []interface{}) int) <-chan TileCount {
+	counts := make(chan TileCount, 1<<10)
+	// no key is deeper than ZMax, so there's nothing to count past it
+	if zmax > ZMax {
+		zmax = ZMax
+	}
+	if zmax < zmin || zmax < 0 {
+		close(counts)
+		return counts
+	}
+	if zmin < 0 {
+		zmin = 0
+	}
+	go func() {
+		defer close(counts)
+		idx.rlockSorted()
 		defer idx.RUnlock()
-		for i := 0; i < len(idx.keys)-1; i++ {
-			qmax := idx.keys[i].qk.Level()
-			for z := zmin; z <= zmax && z <= qmax; z++ {
-				q := idx.keys[i].qk.Parent(z)
-				n := idx.keys[i+1].qk
-				if !n.HasParent(q) {
-					tiles <- q.ToTile()
+		runs := make([]int, zmax-zmin+1)
+		for i, k := range idx.keys {
+			last := i == len(idx.keys)-1
+			var n pkey
+			if !last {
+				n = idx.keys[i+1].qk
+			}
+			for z := zmin; z <= zmax && z <= k.qk.Level(); z++ {
+				runs[z-zmin] += count(idx.values[k.v])
+				q := k.qk.Parent(z)
+				if last || n.Level() < z || n.Parent(z) != q {
+					select {
+					case counts <- TileCount{Tile: q.ToTile(), Count: runs[z-zmin]}:
+					case <-ctx.Done():
+						return
+					}
+					runs[z-zmin] = 0
 				}
 			}
 		}
-		q := idx.keys[len(idx.keys)-1].qk
-		for z := zmin; z <= zmax && z <= len(q); z++ {
-			tiles <- q.Parent(z).ToTile()
-		}
 	}()
-	return tiles
+	return counts
 }
 
-// Values returns a list of values aggregated under the requested tile
+// ForEachTileParallel calls fn for each tile from zmin to zmax with a value under it, like TileRange, using workers goroutines.
+// The keyset is split into contiguous ranges, one per worker, and each tile is passed to fn exactly once by the worker holding the last key under it,
+// so a tile whose keys span ranges isn't repeated. fn is called concurrently and must be safe for use by multiple goroutines.
+// Tiles aren't passed in any particular order. Returns once every worker is done, holding a readlock until then
+func (idx *KeysetIndex) ForEachTileParallel(zmin, zmax, workers int, fn func(Tile)) {
+	if workers < 1 {
+		workers = 1
+	}
+	idx.rlockSorted()
+	defer idx.RUnlock()
+	var wg sync.WaitGroup
+	n := len(idx.keys)
+	for w := 0; w < workers; w++ {
+		lo, hi := n*w/workers, n*(w+1)/workers
+		if lo == hi {
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			idx.tileRange(lo, hi, zmin, zmax, func(t Tile) bool {
+				fn(t)
+				return true
+			})
+		}()
+	}
+	wg.Wait()
+}
+
+// tileRange calls fn for each tile from zmin to zmax that's emitted at the keys in [lo, hi), stopping early if fn returns false.
+// A tile is emitted at the last key under it, which only depends on the key's successor,
+// so disjoint ranges of keys emit disjoint sets of tiles.
+// Caller must hold a readlock on a sorted index
+func (idx *KeysetIndex) tileRange(lo, hi, zmin, zmax int, fn func(Tile) bool) {
+	// negative zooms would shift the packed keys the wrong way
+	if zmin < 0 {
+		zmin = 0
+	}
+	for i := lo; i < hi; i++ {
+		k := idx.keys[i]
+		// the last key has no successor, so all of its parents are emitted
+		last := i == len(idx.keys)-1
+		var n pkey
+		if !last {
+			n = idx.keys[i+1].qk
+		}
+		for z := zmin; z <= zmax && z <= k.qk.Level(); z++ {
+			q := k.qk.Parent(z)
+			if last || n.Level() < z || n.Parent(z) != q {
+				if !fn(q.ToTile()) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// Values returns a list of values aggregated under the requested tile.
+// They're ordered by the quadkey they were added to and in insertion order within a quadkey
 func (idx *KeysetIndex) Values(t Tile) (vals []interface{}) {
-	idx.sort()
-	idx.RLock()
+	return idx.collect(packTile(t))
+}
+
+// ValuesCopy returns the values aggregated under the requested tile like Values, with each one replaced by clone(val).
+// Values holds the stored references, so a deep clone isolates the result from later changes to what they point to.
+// clone is called with a readlock held, so it sees the values as they were at the read and must not modify the index
+func (idx *KeysetIndex) ValuesCopy(t Tile, clone func(val interface{}) interface{}) (vals []interface{}) {
+	idx.rlockSorted()
 	defer idx.RUnlock()
-	qk := t.Quadkey()
-	i := idx.search(qk)
-	for i < len(idx.keys) {
-		n := idx.keys[i]
-		if n.qk == qk || n.qk.HasParent(qk) {
-			vals = append(vals, idx.values[n.v]...)
+	idx.scan(packTile(t), func(k qkey) bool {
+		for _, v := range idx.values[k.v] {
+			vals = append(vals, clone(v))
 		}
-		i++
+		return true
+	})
+	return
+}
+
+// ValuesSorted returns the values aggregated under the requested tile like Values, sorted by less.
+// The sort is stable, so values less considers equal keep the order of Values. The values are collected into a new slice under the readlock
+// and sorted after it's released, so less doesn't hold up writers
+func (idx *KeysetIndex) ValuesSorted(t Tile, less func(a, b interface{}) bool) []interface{} {
+	vals := idx.Values(t)
+	sort.SliceStable(vals, func(i, j int) bool { return less(vals[i], vals[j]) })
+	return vals
+}
+
+// ValuesByQuadkey returns the values aggregated under the quadkey like Values, without converting it to a Tile first.
+// Returns an error if the quadkey is invalid or deeper than ZMax
+func (idx *KeysetIndex) ValuesByQuadkey(qk string) ([]interface{}, error) {
+	k, err := packQuadkey(Quadkey(qk))
+	if err != nil {
+		return nil, err
 	}
+	return idx.collect(k), nil
+}
+
+// LowerBound returns the position in the sorted keyset of the first key with the quadkey as a prefix, or where one would be inserted.
+// Together with UpperBound it's the half-open range of keys under the quadkey, which is only valid until the index is next modified.
+// Returns an error if the quadkey is invalid or deeper than ZMax
+func (idx *KeysetIndex) LowerBound(qk string) (int, error) {
+	k, err := packQuadkey(Quadkey(qk))
+	if err != nil {
+		return 0, err
+	}
+	idx.rlockSorted()
+	defer idx.RUnlock()
+	return idx.search(k), nil
+}
+
+// UpperBound returns the position in the sorted keyset after the last key with the quadkey as a prefix, see LowerBound.
+// Returns an error if the quadkey is invalid or deeper than ZMax
+func (idx *KeysetIndex) UpperBound(qk string) (int, error) {
+	k, err := packQuadkey(Quadkey(qk))
+	if err != nil {
+		return 0, err
+	}
+	idx.rlockSorted()
+	defer idx.RUnlock()
+	return idx.upper(k), nil
+}
+
+// RangeFunc calls fn with the quadkey and values of each key from LowerBound to UpperBound of the quadkey in keyset order, stopping early if fn returns false.
+// The bounds and calls are all under a single readlock, so fn sees a consistent range but must not modify the index.
+// Returns an error if the quadkey is invalid or deeper than ZMax
+func (idx *KeysetIndex) RangeFunc(qk string, fn func(qk Quadkey, vals []interface{}) bool) error {
+	k, err := packQuadkey(Quadkey(qk))
+	if err != nil {
+		return err
+	}
+	idx.rlockSorted()
+	defer idx.RUnlock()
+	for i, hi := idx.search(k), idx.upper(k); i < hi; i++ {
+		if !fn(idx.keys[i].qk.Quadkey(), idx.values[idx.keys[i].v]) {
+			break
+		}
+	}
+	return nil
+}
+
+func (idx *KeysetIndex) collect(qk pkey) (vals []interface{}) {
+	idx.rlockSorted()
+	defer idx.RUnlock()
+	idx.scan(qk, func(k qkey) bool {
+		vals = append(vals, idx.values[k.v]...)
+		return true
+	})
+	return
+}
+
+// ValuesPage returns up to limit of the values aggregated under the requested tile, skipping the first offset, in keyset order.
+// hasMore is true if there are values after the page. Pages are stable while the index isn't modified.
+// A negative offset or limit is treated as 0
+func (idx *KeysetIndex) ValuesPage(t Tile, offset, limit int) (vals []interface{}, hasMore bool) {
+	if offset < 0 {
+		offset = 0
+	}
+	if limit < 0 {
+		limit = 0
+	}
+	idx.rlockSorted()
+	defer idx.RUnlock()
+	idx.scan(packTile(t), func(k qkey) bool {
+		v := idx.values[k.v]
+		if offset >= len(v) {
+			offset -= len(v)
+			return true
+		}
+		v = v[offset:]
+		offset = 0
+		if n := limit - len(vals); len(v) > n {
+			vals = append(vals, v[:n]...)
+			hasMore = true
+			return false
+		}
+		vals = append(vals, v...)
+		return true
+	})
+	return
+}
+
+// ValuesLimited returns the values aggregated under the requested tile like Values, but stops after max of them.
+// truncated is true if values were left out, so coarse tiles on a dense index can't materialize everything beneath them.
+// A negative max is treated as 0
+func (idx *KeysetIndex) ValuesLimited(t Tile, max int) (vals []interface{}, truncated bool) {
+	return idx.ValuesPage(t, 0, max)
+}
+
+// ValuesExact returns the values added to exactly the requested tile, excluding those of its children
+func (idx *KeysetIndex) ValuesExact(t Tile) (vals []interface{}) {
+	idx.rlockSorted()
+	defer idx.RUnlock()
+	qk := packTile(t)
+	idx.scan(qk, func(k qkey) bool {
+		// equal keys sort before their children, so the first longer key ends the match
+		if k.qk != qk {
+			return false
+		}
+		vals = append(vals, idx.values[k.v]...)
+		return true
+	})
+	return
+}
+
+// ValuesMulti returns the values aggregated under each of the tiles, answering them all under one read lock.
+// The tiles are sorted so the keyset is swept once, each search starting where the last left off.
+// Every tile is in the result, with a nil slice if nothing is under it
+func (idx *KeysetIndex) ValuesMulti(tiles []Tile) map[Tile][]interface{} {
+	qks := make([]qkey, len(tiles))
+	for i, t := range tiles {
+		qks[i] = qkey{qk: packTile(t), v: i}
+	}
+	sort.Sort(byQk(qks))
+	vals := make(map[Tile][]interface{}, len(tiles))
+	idx.rlockSorted()
+	defer idx.RUnlock()
+	lo := 0
+	for _, q := range qks {
+		t := tiles[q.v]
+		if _, ok := vals[t]; ok {
+			continue
+		}
+		// a tile's keys start at or after those of any tile sorted before it, including its parents
+		lo += sort.Search(len(idx.keys)-lo, func(i int) bool { return idx.keys[lo+i].qk >= q.qk })
+		var tv []interface{}
+		for _, k := range idx.keys[lo:] {
+			if k.qk != q.qk && !k.qk.HasParent(q.qk) {
+				break
+			}
+			tv = append(tv, idx.values[k.v]...)
+		}
+		vals[t] = tv
+	}
+	return vals
+}
+
+// ValuesInBBox returns the values aggregated under the tiles at zoom that cover the bbox.
+// If minLon > maxLon the bbox is treated as crossing the antimeridian
+func (idx *KeysetIndex) ValuesInBBox(minLat, minLon, maxLat, maxLon float64, zoom int) (vals []interface{}) {
+	tiles := TilesForBBox(minLat, minLon, maxLat, maxLon, zoom)
+	idx.rlockSorted()
+	defer idx.RUnlock()
+	for _, t := range tiles {
+		idx.scan(packTile(t), func(k qkey) bool {
+			vals = append(vals, idx.values[k.v]...)
+			return true
+		})
+	}
+	return
+}
+
+// Coverage returns the fraction of the tiles at zoom covering the bbox that have at least one value under them, 1 once every tile has data.
+// If minLon > maxLon the bbox is treated as crossing the antimeridian
+func (idx *KeysetIndex) Coverage(minLat, minLon, maxLat, maxLon float64, zoom int) float64 {
+	tiles := TilesForBBox(minLat, minLon, maxLat, maxLon, zoom)
+	if len(tiles) == 0 {
+		return 0
+	}
+	idx.rlockSorted()
+	defer idx.RUnlock()
+	covered := 0
+	for _, t := range tiles {
+		idx.scan(packTile(t), func(k qkey) bool {
+			if len(idx.values[k.v]) > 0 {
+				covered++
+				return false
+			}
+			return true
+		})
+	}
+	return float64(covered) / float64(len(tiles))
+}
+
+// Entries returns the values aggregated under the requested tile along with the tile each was added to
+func (idx *KeysetIndex) Entries(t Tile) (entries []Entry) {
+	idx.rlockSorted()
+	defer idx.RUnlock()
+	idx.scan(packTile(t), func(k qkey) bool {
+		tile := k.qk.ToTile()
+		for _, v := range idx.values[k.v] {
+			entries = append(entries, Entry{Tile: tile, Value: v})
+		}
+		return true
+	})
+	return
+}
+
+// TilesOf returns the distinct tiles that val is stored at in quadkey order, using reflect.DeepEqual to compare values
+func (idx *KeysetIndex) TilesOf(val interface{}) (tiles []Tile) {
+	idx.rlockSorted()
+	defer idx.RUnlock()
+	found := false
+	var last pkey
+	for _, k := range idx.keys {
+		// equal keys are adjacent, skip the rest of them once the tile is found
+		if found && k.qk == last {
+			continue
+		}
+		found = false
+		for _, v := range idx.values[k.v] {
+			if reflect.DeepEqual(v, val) {
+				tiles = append(tiles, k.qk.ToTile())
+				found, last = true, k.qk
+				break
+			}
+		}
+	}
+	return
+}
+
+// ForEach calls fn with each value aggregated under the requested tile until fn returns false
+// Holds a readlock for the duration of the walk, so fn must not modify the index
+func (idx *KeysetIndex) ForEach(t Tile, fn func(val interface{}) bool) {
+	idx.rlockSorted()
+	defer idx.RUnlock()
+	idx.scan(packTile(t), func(k qkey) bool {
+		for _, v := range idx.values[k.v] {
+			if !fn(v) {
+				return false
+			}
+		}
+		return true
+	})
+}
+
+// All returns an iterator over every value in the index with the quadkey it was added to, in the order of Values.
+// Ranging over it holds a readlock until the loop ends, so the loop body must not modify the index
+func (idx *KeysetIndex) All() func(yield func(qk Quadkey, val interface{}) bool) {
+	return func(yield func(qk Quadkey, val interface{}) bool) {
+		idx.rlockSorted()
+		defer idx.RUnlock()
+		for _, k := range idx.keys {
+			qk := k.qk.Quadkey()
+			for _, v := range idx.values[k.v] {
+				if !yield(qk, v) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// Count returns the number of values aggregated under the requested tile without copying them
+func (idx *KeysetIndex) Count(t Tile) (n int) {
+	idx.rlockSorted()
+	defer idx.RUnlock()
+	idx.scan(packTile(t), func(k qkey) bool {
+		n += len(idx.values[k.v])
+		return true
+	})
+	return
+}
+
+// Has returns true if any values are aggregated under the requested tile, like Count(t) > 0 but stopping at the first key that has values
+func (idx *KeysetIndex) Has(t Tile) (ok bool) {
+	idx.rlockSorted()
+	defer idx.RUnlock()
+	idx.scan(packTile(t), func(k qkey) bool {
+		ok = len(idx.values[k.v]) > 0
+		return !ok
+	})
 	return
 }
 
 // Add adds a value, but will not be indexed
+// Panics with the error from TryAdd if the index was made by NewKeysetIndexLeaves and the tile overlaps a stored one
 func (idx *KeysetIndex) Add(t Tile, val ...interface{}) {
+	check(idx.TryAdd(t, val...))
+}
+
+// TryAdd is Add that returns an error and adds nothing if the index was made by NewKeysetIndexLeaves
+// and the tile is an ancestor or descendant of a stored tile. Other indexes always add the value
+func (idx *KeysetIndex) TryAdd(t Tile, val ...interface{}) error {
+	if err := idx.tryAdd(t, val); err != nil {
+		return err
+	}
+	idx.emit(EventAdd, t, val...)
+	return nil
+}
+
+func (idx *KeysetIndex) tryAdd(t Tile, val []interface{}) error {
 	idx.Lock()
 	defer idx.Unlock()
+	if err := idx.leaf(idx.key(t), -1); err != nil {
+		return err
+	}
+	idx.log(walAdd, t, "", val)
+	idx.add(t, val)
+	return nil
+}
+
+// AddQuadkey adds the value at the quadkey like Add, for callers that already have quadkeys.
+// Returns an error and adds nothing if the quadkey is invalid or deeper than ZMax, or overlaps a stored one as in TryAdd
+func (idx *KeysetIndex) AddQuadkey(qk string, val interface{}) error {
+	k, err := packQuadkey(Quadkey(qk))
+	if err != nil {
+		return err
+	}
+	return idx.TryAdd(k.ToTile(), val)
+}
+
+// add appends the key and its values without sorting
+// Caller must hold the write lock
+func (idx *KeysetIndex) add(t Tile, val []interface{}) {
 	idx.values = append(idx.values, val)
-	qk := qkey{qk: t.Quadkey(), v: len(idx.values) - 1}
-	idx.keys = append(idx.keys, qk)
-	idx.sorted = false
+	idx.put(qkey{qk: idx.key(t), v: len(idx.values) - 1, at: idx.stamp()})
 }
 
-// sorts the tiles, nothing happens if the sorted flag is set
-func (idx *KeysetIndex) sort() {
+// AddPoint adds the value at the tile containing the coordinate at ZoomForAccuracy(lat, accuracyMeters),
+// the deepest zoom whose tiles are still wider than the accuracy. Longitude is wrapped as in TileFromLatLng
+func (idx *KeysetIndex) AddPoint(lat, lon, accuracyMeters float64, val interface{}) {
+	idx.Add(TileFromLatLng(lat, lon, ZoomForAccuracy(lat, accuracyMeters)), val)
+}
+
+// Delete removes the first value stored at exactly t that is equal to val using reflect.DeepEqual.
+// Returns true if a value was removed
+func (idx *KeysetIndex) Delete(t Tile, val interface{}) (ok bool) {
+	defer func() {
+		if ok {
+			idx.emit(EventDelete, t, val)
+		}
+	}()
+	idx.Lock()
+	defer idx.Unlock()
+	idx.log(walDelete, t, "", []interface{}{val})
+	return idx.delete(t, val)
+}
+
+// delete removes the first value stored at exactly t that is equal to val
+// Caller must hold the write lock
+func (idx *KeysetIndex) delete(t Tile, val interface{}) bool {
+	qk := idx.key(t)
+	for i, k := range idx.keys {
+		if k.qk != qk {
+			continue
+		}
+		vals := idx.values[k.v]
+		for j, v := range vals {
+			if reflect.DeepEqual(v, val) {
+				// full slice expression copies so the caller's variadic slice isn't mutated
+				idx.values[k.v] = append(vals[:j:j], vals[j+1:]...)
+				if len(idx.values[k.v]) == 0 {
+					idx.remove(i)
+				}
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// MapValues replaces each stored value with the result of fn under a write lock.
+// Only values change, so the keys and their sort order are untouched
+func (idx *KeysetIndex) MapValues(fn func(val interface{}) interface{}) {
+	idx.Lock()
+	defer idx.Unlock()
+	for i, vals := range idx.values {
+		// copy so the caller's variadic slice isn't mutated
+		mapped := make([]interface{}, len(vals))
+		for j, v := range vals {
+			mapped[j] = fn(v)
+		}
+		idx.values[i] = mapped
+	}
+}
+
+// Filter removes every value for which fn returns false in a single locked pass, dropping keys left without values.
+// Returns the number of values removed
+func (idx *KeysetIndex) Filter(fn func(val interface{}) bool) (n int) {
+	idx.Lock()
+	defer idx.Unlock()
+	keys := idx.keys[:0]
+	values := make([][]interface{}, 0, len(idx.values))
+	slots := make(map[int]int, len(idx.ids))
+	for _, k := range idx.keys {
+		var kept []interface{}
+		for _, v := range idx.values[k.v] {
+			if fn(v) {
+				kept = append(kept, v)
+			} else {
+				n++
+			}
+		}
+		if len(kept) > 0 {
+			values = append(values, kept)
+			keys = append(keys, qkey{qk: k.qk, v: len(values) - 1, at: k.at})
+			slots[k.v] = len(values) - 1
+		}
+	}
+	idx.keys = keys
+	idx.values = values
+	idx.reslot(func(v int) (s int, ok bool) {
+		s, ok = slots[v]
+		return
+	})
+	return
+}
+
+// Compact sorts the index and collapses the keys at each quadkey into a single key, dropping values equal to one already stored there.
+// Equality uses reflect.DeepEqual. Upserted values are left in their own keys.
+// Returns the number of values removed
+func (idx *KeysetIndex) Compact() (n int) {
+	idx.Lock()
+	defer idx.Unlock()
+	if !idx.sorted {
+		sort.Sort(byQk(idx.keys))
+		idx.sorted = true
+	}
+	owned := make(map[int]bool, len(idx.ids))
+	for _, k := range idx.ids {
+		owned[k.v] = true
+	}
+	slots := make(map[int]int, len(idx.ids))
+	keys := idx.keys[:0]
+	values := make([][]interface{}, 0, len(idx.values))
+	// slot that the values of the current quadkey are merged into and the position of its key, -1 if there isn't one yet
+	slot, merged := -1, -1
+	var prev pkey
+	for _, k := range idx.keys {
+		if k.qk != prev {
+			slot, merged, prev = -1, -1, k.qk
+		}
+		// upserted values keep their own slot, and nothing else is merged into it
+		if owned[k.v] {
+			values = append(values, idx.values[k.v])
+			keys = append(keys, qkey{qk: k.qk, v: len(values) - 1, at: k.at})
+			slots[k.v] = len(values) - 1
+			continue
+		}
+		if slot < 0 {
+			values = append(values, nil)
+			slot = len(values) - 1
+			keys = append(keys, qkey{qk: k.qk, v: slot})
+			merged = len(keys) - 1
+		}
+		// the merged key was last written when the latest of its keys was, upserted keys after it don't count
+		if at := &keys[merged].at; k.at > *at {
+			*at = k.at
+		}
+	next:
+		for _, v := range idx.values[k.v] {
+			for _, o := range values[slot] {
+				if reflect.DeepEqual(v, o) {
+					n++
+					continue next
+				}
+			}
+			values[slot] = append(values[slot], v)
+		}
+	}
+	idx.keys = keys
+	idx.values = values
+	idx.reslot(func(v int) (s int, ok bool) {
+		s, ok = slots[v]
+		return
+	})
+	return
+}
+
+// Shrink sorts the index and rebuilds it into tightly sized slices, with the value slots in quadkey order and keys without values dropped.
+// Deletes and upserts leave spare capacity behind that isn't otherwise released, so it's worth running after a long run of them.
+// Returns the number of keys, value slots and values of capacity reclaimed
+func (idx *KeysetIndex) Shrink() (n int) {
+	idx.Lock()
+	defer idx.Unlock()
 	if !idx.sorted {
-		idx.Lock()
 		sort.Sort(byQk(idx.keys))
 		idx.sorted = true
+	}
+	size := 0
+	for _, k := range idx.keys {
+		if len(idx.values[k.v]) > 0 {
+			size++
+		}
+	}
+	n = cap(idx.keys) + cap(idx.values)
+	for _, vals := range idx.values {
+		n += cap(vals)
+	}
+	keys := make([]qkey, 0, size)
+	values := make([][]interface{}, 0, size)
+	slots := make(map[int]int, len(idx.ids))
+	for _, k := range idx.keys {
+		vals := idx.values[k.v]
+		if len(vals) == 0 {
+			continue
+		}
+		values = append(values, append(make([]interface{}, 0, len(vals)), vals...))
+		keys = append(keys, qkey{qk: k.qk, v: len(values) - 1, at: k.at})
+		slots[k.v] = len(values) - 1
+		n -= len(vals)
+	}
+	n -= cap(keys) + cap(values)
+	idx.keys = keys
+	idx.values = values
+	idx.reslot(func(v int) (s int, ok bool) {
+		s, ok = slots[v]
+		return
+	})
+	return
+}
+
+// removes the key at i and its values, rebasing the value indices of the remaining keys
+// Removing a key doesn't change the order of the others, so the sorted flag stays valid
+// Caller must hold the write lock
+func (idx *KeysetIndex) remove(i int) {
+	v := idx.keys[i].v
+	idx.keys = append(idx.keys[:i], idx.keys[i+1:]...)
+	idx.values = append(idx.values[:v], idx.values[v+1:]...)
+	for j := range idx.keys {
+		if idx.keys[j].v > v {
+			idx.keys[j].v--
+		}
+	}
+	idx.reslot(func(s int) (int, bool) {
+		if s > v {
+			return s - 1, true
+		}
+		return s, s != v
+	})
+}
+
+// Upsert adds val to the tile under id, replacing the value previously upserted with the same id wherever it was stored.
+// Each upserted value is stored in its own slot, so it's unaffected by Compact.
+// Ids are dropped if their value is removed by Delete or Filter, and ids aren't carried over by Merge
+func (idx *KeysetIndex) Upsert(t Tile, id string, val interface{}) {
+	replaced, from, err := idx.tryUpsert(t, id, val)
+	check(err)
+	if len(replaced) > 0 {
+		idx.emit(EventDelete, from, replaced...)
+	}
+	idx.emit(EventUpsert, t, val)
+}
+
+func (idx *KeysetIndex) tryUpsert(t Tile, id string, val interface{}) (replaced []interface{}, from Tile, err error) {
+	idx.Lock()
+	defer idx.Unlock()
+	// the id's previous value is replaced, so its key doesn't count as an overlap
+	own := -1
+	if k, ok := idx.ids[id]; ok {
+		own = k.v
+	}
+	if err = idx.leaf(idx.key(t), own); err != nil {
+		return
+	}
+	idx.log(walUpsert, t, id, []interface{}{val})
+	replaced, from = idx.upsert(t, id, val)
+	return
+}
+
+// upsert stores val under id and returns the values it replaced and the tile they were at
+// Caller must hold the write lock
+func (idx *KeysetIndex) upsert(t Tile, id string, val interface{}) (replaced []interface{}, from Tile) {
+	qk := idx.key(t)
+	if k, ok := idx.ids[id]; ok {
+		replaced, from = idx.values[k.v], k.qk.ToTile()
+		if k.qk == qk {
+			idx.values[k.v] = []interface{}{val}
+			idx.keys[idx.find(k)].at = idx.stamp()
+			return
+		}
+		idx.remove(idx.find(k))
+	}
+	if idx.ids == nil {
+		idx.ids = make(map[string]qkey)
+	}
+	idx.values = append(idx.values, []interface{}{val})
+	k := qkey{qk: qk, v: len(idx.values) - 1, at: idx.stamp()}
+	idx.put(k)
+	idx.ids[id] = k
+	return
+}
+
+// Get returns the value upserted under id if it's stored at exactly t, and false if it isn't.
+// It's the point read for Upsert and doesn't need the index sorted
+func (idx *KeysetIndex) Get(t Tile, id string) (interface{}, bool) {
+	idx.RLock()
+	defer idx.RUnlock()
+	k, ok := idx.ids[id]
+	if !ok || k.qk != idx.key(t) || len(idx.values[k.v]) == 0 {
+		return nil, false
+	}
+	return idx.values[k.v][0], true
+}
+
+// find returns the position of the key in the keyset
+// Caller must hold a lock and the key must be in the keyset
+func (idx *KeysetIndex) find(k qkey) int {
+	i := 0
+	if idx.sorted {
+		i = idx.search(k.qk)
+	}
+	// the key's write time isn't kept up to date in ids, so only the quadkey and slot are compared
+	for idx.keys[i].qk != k.qk || idx.keys[i].v != k.v {
+		i++
+	}
+	return i
+}
+
+// reslot moves the ids to the value slots returned by slot, dropping those whose slot is gone
+// Caller must hold the write lock
+func (idx *KeysetIndex) reslot(slot func(v int) (int, bool)) {
+	for id, k := range idx.ids {
+		if v, ok := slot(k.v); ok {
+			idx.ids[id] = qkey{qk: k.qk, v: v, at: k.at}
+		} else {
+			delete(idx.ids, id)
+		}
+	}
+}
+
+// AddSorted adds values like Add, but inserts the key at its sorted position so queries never need to re-sort.
+// Each insert shifts the keys after it, costing O(n), so it suits workloads that interleave Add and Values.
+// Bulk loads are faster with Add or AddBatch followed by a single sort
+func (idx *KeysetIndex) AddSorted(t Tile, val ...interface{}) {
+	check(idx.addSorted(t, val))
+	idx.emit(EventAdd, t, val...)
+}
+
+func (idx *KeysetIndex) addSorted(t Tile, val []interface{}) error {
+	idx.Lock()
+	defer idx.Unlock()
+	if err := idx.leaf(idx.key(t), -1); err != nil {
+		return err
+	}
+	idx.log(walAdd, t, "", val)
+	if !idx.sorted {
+		sort.Sort(byQk(idx.keys))
+		idx.sorted = true
+	}
+	idx.values = append(idx.values, val)
+	idx.insert(qkey{qk: idx.key(t), v: len(idx.values) - 1, at: idx.stamp()})
+	return nil
+}
+
+// insert adds the key to the sorted keyset after any equal keys to keep them in insertion order
+// Caller must hold the write lock on a sorted index
+func (idx *KeysetIndex) insert(qk qkey) {
+	i := sort.Search(len(idx.keys), func(i int) bool { return idx.keys[i].qk > qk.qk })
+	idx.keys = append(idx.keys, qkey{})
+	copy(idx.keys[i+1:], idx.keys[i:])
+	idx.keys[i] = qk
+}
+
+// Entry is a value stored in an index along with the tile it was added to
+type Entry struct {
+	Tile  Tile
+	Value interface{}
+}
+
+// AddBatch adds all of the entries under a single lock and sorts the index once afterwards.
+// Returns the number of entries added
+func (idx *KeysetIndex) AddBatch(entries []Entry) int {
+	idx.Lock()
+	defer idx.Unlock()
+	idx.append(entries)
+	sort.Sort(byQk(idx.keys))
+	idx.sorted = true
+	return len(entries)
+}
+
+// LoadFrom adds the entries received from ch until it's closed or ctx is done, then sorts the index once.
+// Entries are added in batches so readers aren't locked out for the whole load.
+// Returns ctx.Err() if ctx is done before ch is closed, entries received until then stay in the index
+func (idx *KeysetIndex) LoadFrom(ctx context.Context, ch <-chan Entry) (err error) {
+	defer idx.sort()
+	batch := make([]Entry, 0, 1<<10)
+	flush := func() {
+		idx.Lock()
+		defer idx.Unlock()
+		// a failed WAL write panics in append, so the batch is dropped on the way out and the deferred flush doesn't apply it again
+		defer func() { batch = batch[:0] }()
+		idx.append(batch)
+	}
+	defer flush()
+	for {
+		select {
+		case e, ok := <-ch:
+			if !ok {
+				return nil
+			}
+			batch = append(batch, e)
+			if len(batch) == cap(batch) {
+				flush()
+			}
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// append adds the entries without sorting
+// Caller must hold the write lock
+func (idx *KeysetIndex) append(entries []Entry) {
+	for _, e := range entries {
+		idx.log(walAdd, e.Tile, "", []interface{}{e.Value})
+		idx.values = append(idx.values, []interface{}{e.Value})
+		qk := qkey{qk: idx.key(e.Tile), v: len(idx.values) - 1, at: idx.stamp()}
+		idx.keys = append(idx.keys, qk)
+	}
+	if len(entries) > 0 {
+		idx.sorted = false
+	}
+}
+
+// Merge adds all of the keys and values of other into the index and marks it unsorted.
+// other is copied under its readlock before the index is locked, so the two locks are never held together
+// and merging an index into itself or two indexes into each other concurrently can't deadlock
+func (idx *KeysetIndex) Merge(other *KeysetIndex) {
+	other.RLock()
+	keys := make([]qkey, len(other.keys))
+	copy(keys, other.keys)
+	values := make([][]interface{}, len(other.values))
+	copy(values, other.values)
+	other.RUnlock()
+	idx.Lock()
+	defer idx.Unlock()
+	base := len(idx.values)
+	for _, k := range keys {
+		k.qk, k.v, k.at = idx.truncate(k.qk), k.v+base, idx.stamp()
+		idx.keys = append(idx.keys, k)
+	}
+	idx.values = append(idx.values, values...)
+	if len(keys) > 0 {
+		idx.sorted = false
+	}
+}
+
+// PruneBelow re-keys every value stored deeper than maxZoom to its parent at maxZoom.
+// Aggregated values at maxZoom and shallower are unchanged. Truncating keys keeps their quadkey order,
+// but keys that become equal are re-sorted into insertion order, so a sorted index stays sorted
+func (idx *KeysetIndex) PruneBelow(maxZoom int) {
+	if maxZoom < 0 {
+		maxZoom = 0
+	}
+	idx.Lock()
+	defer idx.Unlock()
+	pruned := false
+	for i, k := range idx.keys {
+		if k.qk.Level() > maxZoom {
+			idx.keys[i].qk = k.qk.Parent(maxZoom)
+			pruned = true
+		}
+	}
+	if pruned && idx.sorted {
+		sort.Sort(byQk(idx.keys))
+	}
+	for id, k := range idx.ids {
+		if k.qk.Level() > maxZoom {
+			idx.ids[id] = qkey{qk: k.qk.Parent(maxZoom), v: k.v, at: k.at}
+		}
+	}
+}
+
+// Snapshot returns a sorted copy of the index that shares its values.
+// Readers of the snapshot never wait on writers to the index, so it can be swapped into place behind a pointer while the index is rebuilt.
+// Changing the snapshot doesn't change the index, but values are shared references.
+// The snapshot keeps the index's max zoom, expiry stamps and leaves only check, but not its WAL, since its writes aren't the index's
+func (idx *KeysetIndex) Snapshot() *KeysetIndex {
+	idx.rlockSorted()
+	defer idx.RUnlock()
+	snap := &KeysetIndex{
+		sorted:  true,
+		keys:    make([]qkey, len(idx.keys)),
+		values:  make([][]interface{}, len(idx.values)),
+		capped:  idx.capped,
+		maxZoom: idx.maxZoom,
+		stamped: idx.stamped,
+		leaves:  idx.leaves,
+	}
+	copy(snap.keys, idx.keys)
+	copy(snap.values, idx.values)
+	if idx.ids != nil {
+		snap.ids = make(map[string]qkey, len(idx.ids))
+		for id, k := range idx.ids {
+			snap.ids[id] = k
+		}
+	}
+	return snap
+}
+
+// MergeAll returns a new KeysetIndex containing the keys and values of all of the indexes
+func MergeAll(idxs ...*KeysetIndex) *KeysetIndex {
+	n := 0
+	for _, o := range idxs {
+		o.RLock()
+		n += len(o.keys)
+		o.RUnlock()
+	}
+	idx := NewKeysetIndex(n)
+	for _, o := range idxs {
+		idx.Merge(o)
+	}
+	return idx
+}
+
+// Reset removes all of the keys and values, keeping the allocated capacity for reuse
+func (idx *KeysetIndex) Reset() {
+	idx.Lock()
+	defer idx.Unlock()
+	// drop references so the old values can be collected
+	for i := range idx.values {
+		idx.values[i] = nil
+	}
+	idx.keys = idx.keys[:0]
+	idx.values = idx.values[:0]
+	idx.ids = nil
+	idx.sorted = true
+}
+
+// SortedKeys returns the quadkeys of the keyset in sorted order, one per key, so a quadkey stored by several writes repeats.
+// Keys at the same quadkey sort by insertion, so the order is reproducible. It's a copy made under a readlock, for debugging
+func (idx *KeysetIndex) SortedKeys() []string {
+	idx.rlockSorted()
+	defer idx.RUnlock()
+	qks := make([]string, len(idx.keys))
+	for i, k := range idx.keys {
+		qks[i] = string(k.qk.Quadkey())
+	}
+	return qks
+}
+
+// Len returns the number of values stored in the index
+func (idx *KeysetIndex) Len() (n int) {
+	idx.RLock()
+	defer idx.RUnlock()
+	for _, vals := range idx.values {
+		n += len(vals)
+	}
+	return
+}
+
+// IndexStats is a summary of the contents of an index
+type IndexStats struct {
+	// Values is the number of values stored
+	Values int
+	// Quadkeys is the number of distinct quadkeys values are stored at
+	Quadkeys int
+	// MinZoom and MaxZoom are the shallowest and deepest levels of the stored quadkeys, 0 if empty
+	MinZoom, MaxZoom int
+}
+
+// Stats returns a summary of the contents of the index
+func (idx *KeysetIndex) Stats() (s IndexStats) {
+	idx.rlockSorted()
+	defer idx.RUnlock()
+	for i, k := range idx.keys {
+		s.Values += len(idx.values[k.v])
+		if i == 0 || k.qk != idx.keys[i-1].qk {
+			s.Quadkeys++
+		}
+		z := k.qk.Level()
+		if i == 0 || z < s.MinZoom {
+			s.MinZoom = z
+		}
+		if z > s.MaxZoom {
+			s.MaxZoom = z
+		}
+	}
+	return
+}
+
+// PopulatedChildren returns the distinct descendants of t at the zoom that have values under them, in quadkey order, in a single scan of the tile's keys.
+// Values added above the zoom aren't under any single descendant, so they're left out. It's empty if zoom isn't deeper than the tile
+func (idx *KeysetIndex) PopulatedChildren(t Tile, zoom int) (tiles []Tile) {
+	if zoom <= t.Z {
+		return
+	}
+	idx.rlockSorted()
+	defer idx.RUnlock()
+	var last pkey
+	idx.scan(packTile(t), func(k qkey) bool {
+		if k.qk.Level() < zoom || len(idx.values[k.v]) == 0 {
+			return true
+		}
+		// sorted keys under the same descendant are adjacent, so it's only a new tile when the prefix changes
+		if q := k.qk.Parent(zoom); len(tiles) == 0 || q != last {
+			last = q
+			tiles = append(tiles, q.ToTile())
+		}
+		return true
+	})
+	return
+}
+
+// ZoomBounds returns the shallowest and deepest zoom of the tiles values were added to, the range worth passing to TileRange.
+// ok is false if the index is empty. It's a single pass that doesn't need the index sorted
+func (idx *KeysetIndex) ZoomBounds() (min, max int, ok bool) {
+	idx.RLock()
+	defer idx.RUnlock()
+	for i, k := range idx.keys {
+		z := k.qk.Level()
+		if i == 0 || z < min {
+			min = z
+		}
+		if z > max {
+			max = z
+		}
+	}
+	return min, max, len(idx.keys) > 0
+}
+
+// Redundancy is a pair of keys in an index where the ancestor's tile contains the descendant's
+type Redundancy struct {
+	Ancestor, Descendant Quadkey
+}
+
+// Redundancies returns every pair of distinct quadkeys in the index where one is an ancestor of the other, in keyset order of the descendant
+// and shallowest ancestor first. Children follow their parents in the sorted keyset, so a single pass keeps the chain of ancestors of the current key
+func (idx *KeysetIndex) Redundancies() (pairs []Redundancy) {
+	idx.rlockSorted()
+	defer idx.RUnlock()
+	var chain []pkey
+	for i, k := range idx.keys {
+		if i > 0 && k.qk == idx.keys[i-1].qk {
+			continue
+		}
+		for len(chain) > 0 && !k.qk.HasParent(chain[len(chain)-1]) {
+			chain = chain[:len(chain)-1]
+		}
+		for _, a := range chain {
+			pairs = append(pairs, Redundancy{Ancestor: a.Quadkey(), Descendant: k.qk.Quadkey()})
+		}
+		chain = append(chain, k.qk)
+	}
+	return
+}
+
+// SizeBytes returns an estimate of the memory held by the index's keyset in a single locked pass.
+// It counts the capacity of the keys, the value slots and the interface headers of the values in them, plus the upserted ids,
+// but not what the values point to or map and allocator overhead, so it's a lower bound for alerting on growth rather than an exact figure
+func (idx *KeysetIndex) SizeBytes() int {
+	idx.RLock()
+	defer idx.RUnlock()
+	var v interface{}
+	n := cap(idx.keys)*int(unsafe.Sizeof(qkey{})) + cap(idx.values)*int(unsafe.Sizeof([]interface{}{}))
+	for _, vals := range idx.values {
+		n += cap(vals) * int(unsafe.Sizeof(v))
+	}
+	for id := range idx.ids {
+		n += len(id) + int(unsafe.Sizeof(id)) + int(unsafe.Sizeof(qkey{}))
+	}
+	return n
+}
+
+// DepthHistogram returns the number of keys stored at each quadkey length, the zoom of the tiles values were added to.
+// Depths without keys are left out. It's a single pass that doesn't need the index sorted
+func (idx *KeysetIndex) DepthHistogram() map[int]int {
+	idx.RLock()
+	defer idx.RUnlock()
+	hist := make(map[int]int)
+	for _, k := range idx.keys {
+		hist[k.qk.Level()]++
+	}
+	return hist
+}
+
+// EnsureSorted sorts the index now if a write left it unsorted, so the next query doesn't pay for the sort.
+// Queries sort an unsorted index under the write lock, blocking every reader for the length of the sort,
+// so call it at a quiescent point after bulk writes to keep that stall off the query path
+func (idx *KeysetIndex) EnsureSorted() {
+	idx.sort()
+}
+
+// sorts the tiles, nothing happens if the sorted flag is set
+func (idx *KeysetIndex) sort() {
+	idx.RLock()
+	sorted := idx.sorted
+	idx.RUnlock()
+	if !sorted {
+		idx.Lock()
+		// another goroutine may have sorted while waiting on the lock
+		if !idx.sorted {
+			sort.Sort(byQk(idx.keys))
+			idx.sorted = true
+		}
 		idx.Unlock()
 	}
 }
 
-func (idx *KeysetIndex) search(qk Quadkey) int {
+// rlockSorted acquires a readlock and guarantees the keys are sorted while it's held
+func (idx *KeysetIndex) rlockSorted() {
+	for {
+		idx.RLock()
+		if idx.sorted {
+			return
+		}
+		idx.RUnlock()
+		idx.sort()
+	}
+}
+
+func (idx *KeysetIndex) search(qk pkey) int {
 	return sort.Search(len(idx.keys), func(i int) bool { return idx.keys[i].qk >= qk })
 }
 
+// upper returns the position after the last key that is qk or one of its children
+// Setting every bit below qk's digits gives a key at least as large as any of its children and smaller than any key after them
+// Caller must hold a readlock on a sorted index
+func (idx *KeysetIndex) upper(qk pkey) int {
+	last := qk | pkey(uint64(1)<<uint(64-2*qk.Level())-1)
+	return sort.Search(len(idx.keys), func(i int) bool { return idx.keys[i].qk > last })
+}
+
+// scan calls fn for each key that is qk or one of its children, stopping early if fn returns false
+// Keys with the qk prefix are contiguous in the sorted keyset, so the scan ends at the first key without it
+// Caller must hold a readlock on a sorted index
+func (idx *KeysetIndex) scan(qk pkey, fn func(k qkey) bool) {
+	for i := idx.search(qk); i < len(idx.keys); i++ {
+		k := idx.keys[i]
+		if k.qk != qk && !k.qk.HasParent(qk) {
+			return
+		}
+		if !fn(k) {
+			return
+		}
+	}
+}
+
 type qkey struct {
-	qk Quadkey
+	qk pkey
 	v  int
+	// at is the unix nanos the key was written if the index is stamped
+	at int64
 }
 
 type byQk []qkey
 
-func (q byQk) Len() int           { return len(q) }
-func (q byQk) Swap(i, j int)      { q[i], q[j] = q[j], q[i] }
-func (q byQk) Less(i, j int) bool { return q[i].qk < q[j].qk }
+func (q byQk) Len() int      { return len(q) }
+func (q byQk) Swap(i, j int) { q[i], q[j] = q[j], q[i] }
+func (q byQk) Less(i, j int) bool {
+	// ties break on the value slot, which follows insertion order, so the order is total and sorts are deterministic
+	if q[i].qk == q[j].qk {
+		return q[i].v < q[j].v
+	}
+	return q[i].qk < q[j].qk
+}
+
+// pkey is a quadkey packed into a uint64 to keep the keyset small.
+// The digits take 2 bits per level left aligned from the high bit and the level is in the low 5 bits,
+// so packed keys sort in the same order as their quadkey strings and a key's children follow it contiguously.
+type pkey uint64
+
+const pkeyLevelMask = 1<<5 - 1
+
+// packTile packs the tile's quadkey. Panics if the tile is invalid, like Tile.Quadkey
+func packTile(t Tile) pkey {
+	if t.Z < 0 || t.Z > ZMax {
+		panic("Invalid tile.Quadkey()")
+	}
+	k := uint64(t.Z)
+	for i := t.Z; i > 0; i-- {
+		m := 1 << uint(i-1)
+		var d uint64
+		if (t.X & m) != 0 {
+			d++
+		}
+		if (t.Y & m) != 0 {
+			d += 2
+		}
+		k |= d << uint(64-2*(t.Z-i+1))
+	}
+	return pkey(k)
+}
+
+// packQuadkey packs the quadkey. Returns an error if the quadkey is invalid or deeper than ZMax
+func packQuadkey(qk Quadkey) (pkey, error) {
+	if qk.Level() > ZMax {
+		return 0, errors.New("Invalid Quadkey " + string(qk))
+	}
+	k := uint64(qk.Level())
+	for i := 0; i < len(qk); i++ {
+		d := qk[i] - '0'
+		if d > 3 {
+			return 0, errors.New("Invalid Quadkey " + string(qk))
+		}
+		k |= uint64(d) << uint(64-2*(i+1))
+	}
+	return pkey(k), nil
+}
+
+// Level returns the depth of the packed quadkey
+func (k pkey) Level() int {
+	return int(k & pkeyLevelMask)
+}
+
+// Parent returns the packed parent at level z, which must be <= k.Level()
+func (k pkey) Parent(z int) pkey {
+	if z == 0 {
+		return 0
+	}
+	digits := uint64(k) & (^uint64(0) << uint(64-2*z))
+	return pkey(digits | uint64(z))
+}
+
+// HasParent returns true if o is a parent of k. If k == o, it returns false
+func (k pkey) HasParent(o pkey) bool {
+	z := o.Level()
+	return k.Level() > z && k.Parent(z) == o
+}
+
+// digit returns the quadkey digit at position i
+func (k pkey) digit(i int) int {
+	return int(uint64(k) >> uint(64-2*(i+1)) & 3)
+}
+
+// Quadkey unpacks the quadkey
+func (k pkey) Quadkey() Quadkey {
+	var qk [ZMax]byte
+	z := k.Level()
+	for i := 0; i < z; i++ {
+		qk[i] = byte('0' + k.digit(i))
+	}
+	return Quadkey(qk[:z])
+}
+
+// ToTile returns the tile represented by the packed quadkey
+func (k pkey) ToTile() (t Tile) {
+	t.Z = k.Level()
+	for i := 0; i < t.Z; i++ {
+		d := k.digit(i)
+		t.X = t.X<<1 | d&1
+		t.Y = t.Y<<1 | d>>1
+	}
+	return
+}
 
 //SuffixIndex is a TileIndex that uses a suffixarray to lookup values
 //It IS NOT currently safe for concurrent access.
@@ -2,10 +2,16 @@ package tiles
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"index/suffixarray"
 	"math/rand"
+	"reflect"
+	"runtime"
+	"sort"
+	"sync"
 	"testing"
+	"time"
 )
 
 //TODO actually write a test
@@ -34,6 +40,1590 @@ func TestTileRange(t *testing.T) {
 	}
 }
 
+func TestTileRangeKeys(t *testing.T) {
+	tests := []struct {
+		qks  []string
+		zmin int
+		zmax int
+		n    int
+	}{
+		{[]string{}, 0, 18, 0},
+		{[]string{"0123"}, 0, 18, 5},
+		{[]string{"0123"}, 1, 2, 2},
+		{[]string{"00", "01"}, 0, 2, 4},
+		{[]string{"00", "01"}, 2, 2, 2},
+		{[]string{"00", "00", "00"}, 0, 2, 3},
+		{[]string{"0", "00", "000"}, 0, 3, 4},
+		{[]string{"0", "0", "01", "1", "1"}, 1, 1, 2},
+	}
+	errf := "KeysetIndex%v.TileRange(%d, %d) -> %d tiles, expected %d"
+	for _, test := range tests {
+		idx := NewTileIndex()
+		for i, qk := range test.qks {
+			tile, _ := FromQuadkeyString(qk)
+			idx.Add(tile, i)
+		}
+		c := 0
+		for range idx.TileRange(test.zmin, test.zmax) {
+			c++
+		}
+		if c != test.n {
+			t.Errorf(errf, test.qks, test.zmin, test.zmax, c, test.n)
+		}
+	}
+}
+
+func TestTileRangeUnique(t *testing.T) {
+	idx := &KeysetIndex{}
+	hydrateIndexN(idx, 1000)
+	seen := make(map[Tile]struct{})
+	for tile := range idx.TileRange(0, 18) {
+		if _, ok := seen[tile]; ok {
+			t.Errorf("TileRange emitted %+v more than once", tile)
+		}
+		seen[tile] = struct{}{}
+	}
+	exp := make(map[Tile]struct{})
+	for _, k := range idx.keys {
+		for z := 0; z <= k.qk.Level(); z++ {
+			exp[k.qk.Parent(z).ToTile()] = struct{}{}
+		}
+	}
+	if len(seen) != len(exp) {
+		t.Errorf("TileRange emitted %d tiles, expected %d", len(seen), len(exp))
+	}
+}
+
+func TestTileRangeZoomGuards(t *testing.T) {
+	idx := &KeysetIndex{}
+	esb := FromCoordinate(40.7484, -73.9857, 18)
+	idx.Add(esb, "EmpireStateBuilding")
+	tests := []struct {
+		zmin, zmax int
+		tiles      []Tile
+	}{
+		{5, 2, nil},
+		{18, 17, nil},
+		{-3, -1, nil},
+		{-3, 0, []Tile{{}}},
+		{-1, 1, []Tile{{}, {X: 0, Y: 0, Z: 1}}},
+		{17, 25, []Tile{esb.Parent(), esb}},
+	}
+	errf := "TileRange(%d, %d) -> %v"
+	for _, test := range tests {
+		var tiles []Tile
+		for tile := range idx.TileRange(test.zmin, test.zmax) {
+			tiles = append(tiles, tile)
+		}
+		if !tileSliceEqual(tiles, test.tiles) {
+			t.Errorf(errf, test.zmin, test.zmax, tiles)
+		}
+	}
+}
+
+func TestTileRangeMixedDepth(t *testing.T) {
+	idx := &KeysetIndex{}
+	esb := FromCoordinate(40.7484, -73.9857, 12)
+	idx.Add(esb, "EmpireStateBuilding")
+	idx.Add(FromCoordinate(40.6892, -74.0445, 9), "StatueOfLiberty")
+	idx.Add(FromCoordinate(51.5007, -0.1246, 12), "BigBen")
+	idx.Add(esb.Parent(), "Midtown")
+	_, deepest, _ := idx.ZoomBounds()
+	for _, zmin := range []int{0, 9, 10, 12} {
+		var exp, tiles []Tile
+		for tile := range idx.TileRange(zmin, deepest) {
+			exp = append(exp, tile)
+		}
+		for tile := range idx.TileRange(zmin, 30) {
+			tiles = append(tiles, tile)
+		}
+		if len(exp) == 0 || !reflect.DeepEqual(tiles, exp) {
+			t.Errorf("TileRange(%d, 30) -> %v != TileRange(%d, %d) -> %v", zmin, tiles, zmin, deepest, exp)
+		}
+	}
+}
+
+// adjacent keys of very different lengths still emit every ancestor in the range once, at the point where the next key leaves it
+func TestTileRangeQuadkeyLengths(t *testing.T) {
+	tests := []struct {
+		qks   []string
+		tiles []Tile
+	}{
+		{[]string{"012", "0123012"}, qkTiles("", "0", "01", "012", "0123", "01230")},
+		{[]string{"0123012", "012"}, qkTiles("", "0", "01", "012", "0123", "01230")},
+		{[]string{"012", "0123012", "02"}, qkTiles("01", "012", "0123", "01230", "", "0", "02")},
+		{[]string{"0123012", "1", "01"}, qkTiles("0", "01", "012", "0123", "01230", "", "1")},
+	}
+	errf := "TileRange(0, 5) of %v -> %v"
+	for _, test := range tests {
+		idx := &KeysetIndex{}
+		for _, qk := range test.qks {
+			check(idx.AddQuadkey(qk, qk))
+		}
+		var tiles []Tile
+		for tile := range idx.TileRange(0, 5) {
+			tiles = append(tiles, tile)
+		}
+		if !reflect.DeepEqual(tiles, test.tiles) {
+			t.Errorf(errf, test.qks, tiles)
+		}
+	}
+}
+
+func TestKeysetIndexTiles(t *testing.T) {
+	idx := &KeysetIndex{}
+	hydrateIndexN(idx, 1000)
+	idx.Add(FromCoordinate(51.5007, -0.1246, 12), "BigBen")
+	for _, zr := range [][2]int{{0, 18}, {5, 12}, {-1, 30}, {18, 18}, {5, 2}, {-3, -1}} {
+		var exp []Tile
+		for tile := range idx.TileRange(zr[0], zr[1]) {
+			exp = append(exp, tile)
+		}
+		if tiles := idx.Tiles(zr[0], zr[1]); !reflect.DeepEqual(tiles, exp) {
+			t.Errorf("Tiles(%d, %d) differs from TileRange: %d != %d tiles", zr[0], zr[1], len(tiles), len(exp))
+		}
+	}
+}
+
+func TestKeysetIndexTileRangeUnder(t *testing.T) {
+	idx := &KeysetIndex{}
+	hydrateIndexN(idx, 1000)
+	idx.Add(FromCoordinate(51.5007, -0.1246, 12), "BigBen")
+	idx.Add(FromCoordinate(40.7484, -73.9857, 10), "Midtown")
+	idx.Add(Tile{}, "World")
+	nyc := Tile{X: 75, Y: 96, Z: 8}
+	tests := []struct {
+		root       Tile
+		zmin, zmax int
+	}{
+		{nyc, 0, 18},
+		{nyc, 10, 12},
+		{nyc, 8, 8},
+		{FromCoordinate(40.7484, -73.9857, 14), 0, 30},
+		{Tile{}, -1, 30},
+		{Tile{X: 106, Y: 194, Z: 9}, 0, 18},
+		{nyc, 5, 7},
+	}
+	errf := "TileRangeUnder(%v, %d, %d) -> %d tiles != %d"
+	for _, test := range tests {
+		var exp, tiles []Tile
+		for tile := range idx.TileRange(test.zmin, test.zmax) {
+			if tile.Z >= test.root.Z && test.root.ContainsTile(tile) {
+				exp = append(exp, tile)
+			}
+		}
+		for tile := range idx.TileRangeUnder(test.root, test.zmin, test.zmax) {
+			tiles = append(tiles, tile)
+		}
+		if !reflect.DeepEqual(tiles, exp) {
+			t.Errorf(errf, test.root, test.zmin, test.zmax, len(tiles), len(exp))
+		}
+	}
+}
+
+func TestKeysetIndexTileRangeUnderContext(t *testing.T) {
+	idx := &KeysetIndex{}
+	for i := 0; i < 1<<12; i++ {
+		idx.Add(Tile{X: i, Y: i, Z: 18}, i)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	for range idx.TileRangeUnderContext(ctx, Tile{}, 0, 18) {
+		break
+	}
+	cancel()
+	done := make(chan struct{})
+	go func() {
+		idx.Add(Tile{X: 1, Y: 1, Z: 18}, "after")
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Error("TileRangeUnderContext did not release the lock after cancel")
+	}
+}
+
+func TestTileRangeCounts(t *testing.T) {
+	idx := &KeysetIndex{}
+	hydrateIndexN(idx, 1000)
+	idx.Add(FromCoordinate(51.5007, -0.1246, 12), "BigBen", "Parliament")
+	idx.Add(FromCoordinate(40.7484, -73.9857, 10), "Midtown")
+	idx.Add(Tile{}, "World")
+	for _, zr := range [][2]int{{0, 18}, {5, 12}, {-1, 30}, {18, 18}} {
+		var tiles []Tile
+		for c := range idx.TileRangeCounts(zr[0], zr[1]) {
+			tiles = append(tiles, c.Tile)
+			if n := idx.Count(c.Tile); c.Count != n {
+				t.Errorf("TileRangeCounts(%d, %d) %v -> %d != Count %d", zr[0], zr[1], c.Tile, c.Count, n)
+			}
+		}
+		var exp []Tile
+		for tile := range idx.TileRange(zr[0], zr[1]) {
+			exp = append(exp, tile)
+		}
+		if !reflect.DeepEqual(tiles, exp) {
+			t.Errorf("TileRangeCounts(%d, %d) tiles differ from TileRange", zr[0], zr[1])
+		}
+	}
+	for _, zr := range [][2]int{{5, 2}, {25, 30}} {
+		if _, ok := <-idx.TileRangeCounts(zr[0], zr[1]); ok {
+			t.Errorf("TileRangeCounts(%d, %d) sent a tile", zr[0], zr[1])
+		}
+	}
+}
+
+func TestTileRangeBuffered(t *testing.T) {
+	idx := &KeysetIndex{}
+	hydrateIndexN(idx, 100)
+	var exp []Tile
+	for tile := range idx.TileRange(0, 18) {
+		exp = append(exp, tile)
+	}
+	for _, buffer := range []int{-1, 0, 1, 1 << 12} {
+		ch := idx.TileRangeBuffered(0, 18, buffer)
+		if c, b := cap(ch), buffer; c != b && !(b < 0 && c == 0) {
+			t.Errorf("TileRangeBuffered(0, 18, %d) cap -> %d", buffer, c)
+		}
+		var tiles []Tile
+		for tile := range ch {
+			tiles = append(tiles, tile)
+		}
+		if !tileSliceEqual(tiles, exp) {
+			t.Errorf("TileRangeBuffered(0, 18, %d) -> %d tiles, expected %d", buffer, len(tiles), len(exp))
+		}
+	}
+	if c := cap(idx.TileRange(0, 18)); c != 1<<10 {
+		t.Errorf("TileRange cap -> %d", c)
+	}
+}
+
+func TestTileRangeContext(t *testing.T) {
+	idx := &KeysetIndex{}
+	for i := 0; i < 1<<12; i++ {
+		idx.Add(Tile{X: i, Y: i, Z: 18}, i)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	for range idx.TileRangeContext(ctx, 0, 18) {
+		break
+	}
+	cancel()
+	done := make(chan struct{})
+	go func() {
+		idx.Add(Tile{X: 1, Y: 1, Z: 18}, "after")
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Error("TileRangeContext did not release the lock after cancel")
+	}
+}
+
+func TestTileRangeCountsContext(t *testing.T) {
+	idx := &KeysetIndex{}
+	for i := 0; i < 1<<12; i++ {
+		idx.Add(Tile{X: i, Y: i, Z: 18}, i)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	for range idx.TileRangeCountsContext(ctx, 0, 18) {
+		break
+	}
+	cancel()
+	done := make(chan struct{})
+	go func() {
+		idx.Add(Tile{X: 1, Y: 1, Z: 18}, "after")
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Error("TileRangeCountsContext did not release the lock after cancel")
+	}
+}
+
+func TestForEachTileParallel(t *testing.T) {
+	idx := &KeysetIndex{}
+	hydrateIndexN(idx, 1000)
+	nyc := Tile{X: 75, Y: 96, Z: 8}
+	idx.Add(nyc, "NewYork")
+	idx.Add(nyc, "Manhattan")
+	tests := []struct {
+		zmin, zmax, workers int
+	}{
+		{0, 18, 1},
+		{0, 18, 4},
+		{0, 18, 7},
+		{8, 12, 16},
+		{0, 18, 0},
+		{0, 18, 5000},
+	}
+	errf := "ForEachTileParallel(%d, %d, %d) %s"
+	for _, test := range tests {
+		var mu sync.Mutex
+		seen := make(map[Tile]int)
+		idx.ForEachTileParallel(test.zmin, test.zmax, test.workers, func(tile Tile) {
+			mu.Lock()
+			seen[tile]++
+			mu.Unlock()
+		})
+		exp := 0
+		for tile := range idx.TileRange(test.zmin, test.zmax) {
+			if seen[tile] != 1 {
+				t.Errorf(errf, test.zmin, test.zmax, test.workers, fmt.Sprintf("passed %v %d times", tile, seen[tile]))
+			}
+			exp++
+		}
+		if len(seen) != exp {
+			t.Errorf(errf, test.zmin, test.zmax, test.workers, fmt.Sprintf("passed %d tiles, expected %d", len(seen), exp))
+		}
+	}
+	(&KeysetIndex{}).ForEachTileParallel(0, 18, 4, func(tile Tile) {
+		t.Errorf("ForEachTileParallel on an empty index passed %v", tile)
+	})
+}
+
+func TestKeysetIndexEnsureSorted(t *testing.T) {
+	idx := &KeysetIndex{}
+	idx.EnsureSorted()
+	hydrateIndexN(idx, 1000)
+	if idx.sorted {
+		t.Fatal("Add left the index sorted")
+	}
+	var wg sync.WaitGroup
+	for g := 0; g < 4; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			idx.EnsureSorted()
+		}()
+	}
+	wg.Wait()
+	if !idx.sorted || !sort.IsSorted(byQk(idx.keys)) {
+		t.Error("EnsureSorted didn't sort the index")
+	}
+}
+
+func TestKeysetIndexConcurrent(t *testing.T) {
+	idx := &KeysetIndex{}
+	nyc := Tile{X: 75, Y: 96, Z: 8}
+	var wg sync.WaitGroup
+	for g := 0; g < 16; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < 100; i++ {
+				idx.Add(FromCoordinate(40.7484, -73.9857, 18), g*100+i)
+				idx.Values(nyc)
+			}
+		}(g)
+	}
+	wg.Wait()
+	if n := len(idx.Values(nyc)); n != 1600 {
+		t.Error("Concurrent Add/Values expected 1600 values, got ", n)
+	}
+}
+
+func TestKeysetIndexDelete(t *testing.T) {
+	idx := &KeysetIndex{}
+	esb := FromCoordinate(40.7484, -73.9857, 18)
+	sol := FromCoordinate(40.6892, -74.0445, 18)
+	bbn := FromCoordinate(51.5007, -0.1246, 18)
+	nyc := Tile{X: 75, Y: 96, Z: 8}
+	idx.Add(bbn, "BigBen")
+	idx.Add(esb, "EmpireStateBuilding")
+	idx.Add(sol, "StatueOfLiberty", "LibertyIsland")
+	idx.Add(esb, "ChryslerBuilding")
+	if n := len(idx.Values(nyc)); n != 4 {
+		t.Fatal("NYC expected 4 values before Delete, got ", n)
+	}
+	tests := []struct {
+		tile Tile
+		val  interface{}
+		ok   bool
+		nyc  int
+	}{
+		{sol, "Missing", false, 4},
+		{nyc, "StatueOfLiberty", false, 4},
+		{sol, "StatueOfLiberty", true, 3},
+		{esb, "EmpireStateBuilding", true, 2},
+		{esb, "EmpireStateBuilding", false, 2},
+		{sol, "LibertyIsland", true, 1},
+	}
+	errf := "KeysetIndex.Delete(%+v, %v) -> %v"
+	for _, test := range tests {
+		if ok := idx.Delete(test.tile, test.val); ok != test.ok {
+			t.Errorf(errf, test.tile, test.val, ok)
+		}
+		if n := len(idx.Values(nyc)); n != test.nyc {
+			t.Errorf("NYC expected %d values after Delete(%v), got %d", test.nyc, test.val, n)
+		}
+	}
+	if vals := idx.Values(esb); len(vals) != 1 || vals[0] != "ChryslerBuilding" {
+		t.Error("ESB: ", vals)
+	}
+	if vals := idx.Values(bbn); len(vals) != 1 || vals[0] != "BigBen" {
+		t.Error("BBN: ", vals)
+	}
+	if len(idx.keys) != 2 || len(idx.values) != 2 {
+		t.Errorf("Delete did not compact keys %d and values %d", len(idx.keys), len(idx.values))
+	}
+}
+
+func TestKeysetIndexAddBatch(t *testing.T) {
+	idx := &KeysetIndex{}
+	idx.Add(FromCoordinate(40.6892, -74.0445, 18), "StatueOfLiberty")
+	n := idx.AddBatch([]Entry{
+		{FromCoordinate(40.7484, -73.9857, 18), "EmpireStateBuilding"},
+		{FromCoordinate(51.5007, -0.1246, 18), "BigBen"},
+	})
+	if n != 2 {
+		t.Error("AddBatch expected to add 2 entries, got ", n)
+	}
+	if !idx.sorted {
+		t.Error("AddBatch did not sort the index")
+	}
+	if vals := idx.Values(Tile{X: 75, Y: 96, Z: 8}); len(vals) != 2 {
+		t.Error("NYC: ", vals)
+	}
+}
+
+func TestKeysetIndexHas(t *testing.T) {
+	idx := &KeysetIndex{}
+	esb := FromCoordinate(40.7484, -73.9857, 18)
+	sol := FromCoordinate(40.6892, -74.0445, 18)
+	den := FromCoordinate(39.7392, -104.9903, 12)
+	idx.Add(esb, "EmpireStateBuilding")
+	idx.Add(den)
+	tests := []struct {
+		tile Tile
+		ok   bool
+	}{
+		{esb, true},
+		{sol, false},
+		{Tile{X: 75, Y: 96, Z: 8}, true},
+		{den, false},
+		{Tile{}, true},
+	}
+	errf := "KeysetIndex.Has(%+v) -> %t"
+	for _, test := range tests {
+		if ok := idx.Has(test.tile); ok != test.ok || ok != (idx.Count(test.tile) > 0) {
+			t.Errorf(errf, test.tile, ok)
+		}
+	}
+}
+
+func TestKeysetIndexCount(t *testing.T) {
+	idx := &KeysetIndex{}
+	esb := FromCoordinate(40.7484, -73.9857, 18)
+	sol := FromCoordinate(40.6892, -74.0445, 18)
+	bbn := FromCoordinate(51.5007, -0.1246, 18)
+	idx.Add(esb, "EmpireStateBuilding", "ChryslerBuilding")
+	idx.Add(sol, "StatueOfLiberty")
+	idx.Add(bbn, "BigBen")
+	tests := []struct {
+		tile Tile
+		n    int
+	}{
+		{esb, 2},
+		{sol, 1},
+		{Tile{X: 75, Y: 96, Z: 8}, 3},
+		{Tile{X: 106, Y: 194, Z: 9}, 0},
+		{Tile{}, 4},
+	}
+	errf := "KeysetIndex.Count(%+v) -> %d"
+	for _, test := range tests {
+		if n := idx.Count(test.tile); n != test.n {
+			t.Errorf(errf, test.tile, n)
+		}
+	}
+}
+
+func TestKeysetIndexForEach(t *testing.T) {
+	idx := &KeysetIndex{}
+	esb := FromCoordinate(40.7484, -73.9857, 18)
+	sol := FromCoordinate(40.6892, -74.0445, 18)
+	idx.Add(esb, "EmpireStateBuilding", "ChryslerBuilding")
+	idx.Add(sol, "StatueOfLiberty")
+	nyc := Tile{X: 75, Y: 96, Z: 8}
+	var vals []interface{}
+	idx.ForEach(nyc, func(val interface{}) bool {
+		vals = append(vals, val)
+		return true
+	})
+	if len(vals) != 3 {
+		t.Error("ForEach NYC: ", vals)
+	}
+	c := 0
+	idx.ForEach(nyc, func(val interface{}) bool {
+		c++
+		return val != "ChryslerBuilding"
+	})
+	if c != 2 {
+		t.Error("ForEach did not stop early, visited ", c)
+	}
+}
+
+func TestKeysetIndexMerge(t *testing.T) {
+	esb := FromCoordinate(40.7484, -73.9857, 18)
+	sol := FromCoordinate(40.6892, -74.0445, 18)
+	bbn := FromCoordinate(51.5007, -0.1246, 18)
+	nyc := Tile{X: 75, Y: 96, Z: 8}
+	a, b, c := &KeysetIndex{}, &KeysetIndex{}, &KeysetIndex{}
+	a.Add(esb, "EmpireStateBuilding")
+	b.Add(sol, "StatueOfLiberty", "LibertyIsland")
+	c.Add(bbn, "BigBen")
+	all := MergeAll(a, b, c)
+	if n := all.Count(nyc); n != 3 {
+		t.Error("MergeAll NYC expected 3 values, got ", n)
+	}
+	if vals := all.Values(bbn); len(vals) != 1 || vals[0] != "BigBen" {
+		t.Error("MergeAll BBN: ", vals)
+	}
+	a.Merge(b)
+	if vals := a.Values(sol); len(vals) != 2 || vals[0] != "StatueOfLiberty" {
+		t.Error("Merge SOL: ", vals)
+	}
+	if vals := a.Values(esb); len(vals) != 1 || vals[0] != "EmpireStateBuilding" {
+		t.Error("Merge ESB: ", vals)
+	}
+	a.Merge(a)
+	if n := a.Count(nyc); n != 6 {
+		t.Error("Merge into self NYC expected 6 values, got ", n)
+	}
+	if n := b.Count(nyc); n != 2 {
+		t.Error("Merge modified other, NYC has ", n)
+	}
+}
+
+func TestKeysetIndexReset(t *testing.T) {
+	idx := NewKeysetIndex(4)
+	testIndex(t, idx)
+	idx.Reset()
+	if len(idx.keys) != 0 || len(idx.values) != 0 || cap(idx.keys) != 4 || cap(idx.values) != 4 {
+		t.Errorf("Reset() -> keys %d/%d, values %d/%d", len(idx.keys), cap(idx.keys), len(idx.values), cap(idx.values))
+	}
+	if n := idx.Count(Tile{}); n != 0 {
+		t.Error("Reset() left values in the index ", n)
+	}
+	testIndex(t, idx)
+}
+
+func TestKeysetIndexAddSorted(t *testing.T) {
+	idx := &KeysetIndex{}
+	idx.Add(FromCoordinate(51.5007, -0.1246, 18), "BigBen")
+	for i := 0; i < 100; i++ {
+		idx.AddSorted(Tile{X: rand.Intn(1 << 18), Y: rand.Intn(1 << 18), Z: 18}, i)
+		if !idx.sorted {
+			t.Fatal("AddSorted left the index unsorted")
+		}
+	}
+	if !sort.IsSorted(byQk(idx.keys)) {
+		t.Error("AddSorted keys are not sorted")
+	}
+	esb := FromCoordinate(40.7484, -73.9857, 18)
+	idx.AddSorted(esb, "EmpireStateBuilding")
+	idx.AddSorted(esb, "ChryslerBuilding")
+	if vals := idx.Values(esb); len(vals) != 2 || vals[0] != "EmpireStateBuilding" {
+		t.Error("AddSorted ESB: ", vals)
+	}
+	if n := idx.Count(Tile{}); n != 103 {
+		t.Error("AddSorted expected 103 values, got ", n)
+	}
+}
+
+func TestKeysetIndexStats(t *testing.T) {
+	idx := &KeysetIndex{}
+	if n, s := idx.Len(), idx.Stats(); n != 0 || s != (IndexStats{}) {
+		t.Errorf("empty KeysetIndex Len() -> %d, Stats() -> %+v", n, s)
+	}
+	esb := FromCoordinate(40.7484, -73.9857, 18)
+	idx.Add(esb, "EmpireStateBuilding", "ChryslerBuilding")
+	idx.Add(esb, "Macy's")
+	idx.Add(FromCoordinate(40.6892, -74.0445, 12), "StatueOfLiberty")
+	idx.Add(Tile{X: 75, Y: 96, Z: 8}, "NYC")
+	exp := IndexStats{Values: 5, Quadkeys: 3, MinZoom: 8, MaxZoom: 18}
+	if n, s := idx.Len(), idx.Stats(); n != 5 || s != exp {
+		t.Errorf("KeysetIndex Len() -> %d, Stats() -> %+v", n, s)
+	}
+}
+
+func TestKeysetIndexMaxZoom(t *testing.T) {
+	if z, ok := NewKeysetIndex(0).MaxZoom(); z != 0 || ok {
+		t.Errorf("NewKeysetIndex MaxZoom() -> %d, %t", z, ok)
+	}
+	idx := NewKeysetIndexMaxZoom(0, 12)
+	if z, ok := idx.MaxZoom(); z != 12 || !ok {
+		t.Errorf("NewKeysetIndexMaxZoom MaxZoom() -> %d, %t", z, ok)
+	}
+	esb := FromCoordinate(40.7484, -73.9857, 18)
+	sol := FromCoordinate(40.6892, -74.0445, 18)
+	esb12 := esb.Quadkey().Parent(12).ToTile()
+	idx.Add(esb, "EmpireStateBuilding")
+	idx.AddSorted(sol, "StatueOfLiberty")
+	idx.AddBatch([]Entry{{esb, "ChryslerBuilding"}})
+	idx.Upsert(esb, "ferry", "Ferry")
+	idx.Add(Tile{X: 75, Y: 96, Z: 8}, "NewYork")
+	other := &KeysetIndex{}
+	other.Add(esb, "Macy's")
+	idx.Merge(other)
+	if _, max, _ := idx.ZoomBounds(); max != 12 {
+		t.Errorf("NewKeysetIndexMaxZoom ZoomBounds max -> %d", max)
+	}
+	if vals := idx.ValuesExact(esb12); !reflect.DeepEqual(vals, []interface{}{"EmpireStateBuilding", "ChryslerBuilding", "Ferry", "Macy's"}) {
+		t.Errorf("NewKeysetIndexMaxZoom ValuesExact(%v) -> %v", esb12, vals)
+	}
+	if !idx.Delete(esb, "Macy's") || idx.Count(esb12) != 3 {
+		t.Errorf("NewKeysetIndexMaxZoom Delete(%v) didn't remove the truncated value", esb)
+	}
+	for tile := range idx.TileRange(0, ZMax) {
+		if tile.Z > 12 {
+			t.Errorf("NewKeysetIndexMaxZoom TileRange -> %v", tile)
+		}
+	}
+	data, _ := other.MarshalJSON()
+	if err := idx.UnmarshalJSON(data); err != nil || len(idx.ValuesExact(esb12)) != 1 {
+		t.Errorf("NewKeysetIndexMaxZoom UnmarshalJSON -> %v, %v", idx.ValuesExact(esb12), err)
+	}
+	if z, _ := NewKeysetIndexMaxZoom(0, 40).MaxZoom(); z != ZMax {
+		t.Errorf("NewKeysetIndexMaxZoom(0, 40) MaxZoom() -> %d", z)
+	}
+}
+
+func TestKeysetIndexAll(t *testing.T) {
+	idx := &KeysetIndex{}
+	for range idx.All() {
+		t.Error("All on an empty index yielded")
+	}
+	testIndex(t, idx)
+	idx.Add(Tile{X: 75, Y: 96, Z: 8}, "NewYork", "Manhattan")
+	var qks []Quadkey
+	var vals []interface{}
+	for qk, v := range idx.All() {
+		qks = append(qks, qk)
+		vals = append(vals, v)
+	}
+	if !reflect.DeepEqual(vals, idx.Values(Tile{})) || len(qks) != 5 || qks[1] != "03201011" || qks[2] != qks[1] {
+		t.Errorf("All -> %v, %v", qks, vals)
+	}
+	n := 0
+	for range idx.All() {
+		if n++; n == 2 {
+			break
+		}
+	}
+	// breaking out of the loop releases the readlock
+	idx.Add(Tile{}, "World")
+	if n != 2 || idx.Len() != 6 {
+		t.Errorf("All stopped after %d", n)
+	}
+}
+
+func TestKeysetIndexPopulatedChildren(t *testing.T) {
+	idx := &KeysetIndex{}
+	esb := FromCoordinate(40.7484, -73.9857, 18)
+	sol := FromCoordinate(40.6892, -74.0445, 18)
+	bbn := FromCoordinate(51.5007, -0.1246, 18)
+	nyc := Tile{X: 75, Y: 96, Z: 8}
+	idx.Add(esb, "EmpireStateBuilding", "ChryslerBuilding")
+	idx.Add(sol, "StatueOfLiberty")
+	idx.Add(bbn, "BigBen")
+	idx.Add(nyc, "NewYork")
+	den := FromCoordinate(39.7392, -104.9903, 12)
+	idx.Add(den)
+	tests := []struct {
+		tile  Tile
+		zoom  int
+		tiles []Tile
+	}{
+		{Tile{}, 8, []Tile{bbn.Quadkey()[:8].ToTile(), nyc}},
+		{nyc, 18, []Tile{esb, sol}},
+		{nyc, 12, []Tile{esb.Quadkey()[:12].ToTile(), sol.Quadkey()[:12].ToTile()}},
+		{nyc, 9, []Tile{esb.Quadkey()[:9].ToTile()}},
+		{nyc, 8, nil},
+		{nyc, 19, nil},
+		{den.Quadkey()[:9].ToTile(), 12, nil},
+	}
+	errf := "PopulatedChildren(%v, %d) -> %v"
+	for _, test := range tests {
+		if tiles := idx.PopulatedChildren(test.tile, test.zoom); !reflect.DeepEqual(tiles, test.tiles) {
+			t.Errorf(errf, test.tile, test.zoom, tiles)
+		}
+	}
+}
+
+func TestKeysetIndexZoomBounds(t *testing.T) {
+	idx := &KeysetIndex{}
+	if min, max, ok := idx.ZoomBounds(); min != 0 || max != 0 || ok {
+		t.Errorf("empty KeysetIndex ZoomBounds() -> %d, %d, %t", min, max, ok)
+	}
+	tests := []struct {
+		tile     Tile
+		min, max int
+	}{
+		{FromCoordinate(40.7484, -73.9857, 18), 18, 18},
+		{FromCoordinate(40.6892, -74.0445, 12), 12, 18},
+		{FromCoordinate(51.5007, -0.1246, 20), 12, 20},
+		{Tile{}, 0, 20},
+	}
+	errf := "ZoomBounds() after Add(%v) -> %d, %d, %t"
+	for _, test := range tests {
+		idx.Add(test.tile, test.tile.String())
+		if min, max, ok := idx.ZoomBounds(); min != test.min || max != test.max || !ok {
+			t.Errorf(errf, test.tile, min, max, ok)
+		}
+	}
+}
+
+func TestKeysetIndexRedundancies(t *testing.T) {
+	idx := &KeysetIndex{}
+	if pairs := idx.Redundancies(); pairs != nil {
+		t.Error("Redundancies on an empty index: ", pairs)
+	}
+	esb := FromCoordinate(40.7484, -73.9857, 18)
+	sol := FromCoordinate(40.6892, -74.0445, 18)
+	bbn := FromCoordinate(51.5007, -0.1246, 18)
+	nyc := Tile{X: 75, Y: 96, Z: 8}
+	idx.Add(esb, "EmpireStateBuilding")
+	idx.Add(sol, "StatueOfLiberty")
+	idx.Add(bbn, "BigBen")
+	if pairs := idx.Redundancies(); pairs != nil {
+		t.Error("Redundancies without ancestors: ", pairs)
+	}
+	idx.Add(nyc, "NewYork")
+	idx.Add(nyc, "Manhattan")
+	idx.Add(esb, "ChryslerBuilding")
+	idx.Add(FromCoordinate(40.7484, -73.9857, 12), "Midtown")
+	mid := esb.Quadkey()[:12]
+	exp := []Redundancy{
+		{nyc.Quadkey(), mid},
+		{nyc.Quadkey(), esb.Quadkey()},
+		{mid, esb.Quadkey()},
+		{nyc.Quadkey(), sol.Quadkey()},
+	}
+	if pairs := idx.Redundancies(); !reflect.DeepEqual(pairs, exp) {
+		t.Errorf("KeysetIndex.Redundancies() -> %v", pairs)
+	}
+}
+
+func TestKeysetIndexSizeBytes(t *testing.T) {
+	idx := &KeysetIndex{}
+	if n := idx.SizeBytes(); n != 0 {
+		t.Error("SizeBytes on an empty index: ", n)
+	}
+	idx = NewKeysetIndex(100)
+	empty := idx.SizeBytes()
+	if empty <= 0 {
+		t.Error("SizeBytes with capacity: ", empty)
+	}
+	hydrateIndexN(idx, 1000)
+	full := idx.SizeBytes()
+	if full <= empty {
+		t.Errorf("SizeBytes after adding -> %d <= %d", full, empty)
+	}
+	idx.Upsert(Tile{}, "world", "World")
+	if n := idx.SizeBytes(); n <= full {
+		t.Errorf("SizeBytes after upserting -> %d <= %d", n, full)
+	}
+	for i := 0; i < 990; i++ {
+		idx.Delete(idx.keys[0].qk.ToTile(), idx.values[idx.keys[0].v][0])
+	}
+	before := idx.SizeBytes()
+	idx.Shrink()
+	if n := idx.SizeBytes(); n >= before {
+		t.Errorf("SizeBytes after Shrink -> %d >= %d", n, before)
+	}
+}
+
+func TestKeysetIndexDepthHistogram(t *testing.T) {
+	idx := &KeysetIndex{}
+	if hist := idx.DepthHistogram(); len(hist) != 0 {
+		t.Errorf("empty KeysetIndex DepthHistogram() -> %v", hist)
+	}
+	idx.Add(FromCoordinate(40.7484, -73.9857, 18), "EmpireStateBuilding")
+	idx.Add(FromCoordinate(40.6892, -74.0445, 18), "StatueOfLiberty")
+	idx.Add(FromCoordinate(51.5007, -0.1246, 12), "BigBen")
+	idx.Add(Tile{}, "World")
+	hist := idx.DepthHistogram()
+	if exp := map[int]int{0: 1, 12: 1, 18: 2}; !reflect.DeepEqual(hist, exp) {
+		t.Errorf("KeysetIndex.DepthHistogram() -> %v != %v", hist, exp)
+	}
+}
+
+func TestKeysetIndexEntries(t *testing.T) {
+	idx := &KeysetIndex{}
+	esb := FromCoordinate(40.7484, -73.9857, 18)
+	sol := FromCoordinate(40.6892, -74.0445, 18)
+	idx.Add(esb, "EmpireStateBuilding")
+	idx.Add(sol, "StatueOfLiberty")
+	idx.Add(FromCoordinate(51.5007, -0.1246, 18), "BigBen")
+	entries := idx.Entries(Tile{X: 75, Y: 96, Z: 8})
+	exp := []Entry{{esb, "EmpireStateBuilding"}, {sol, "StatueOfLiberty"}}
+	if !reflect.DeepEqual(entries, exp) {
+		t.Error("Entries NYC: ", entries)
+	}
+	if entries := idx.Entries(Tile{X: 106, Y: 194, Z: 9}); len(entries) != 0 {
+		t.Error("Entries DEN: ", entries)
+	}
+}
+
+func TestKeysetIndexValuesNoPrefixMatch(t *testing.T) {
+	idx := &KeysetIndex{}
+	// keys either side of 0231010 in sort order, none of which have it as a prefix
+	for _, qk := range []Quadkey{"023101", "0231003", "0231011", "02310110", "0231020"} {
+		idx.Add(qk.ToTile(), string(qk))
+	}
+	tests := []struct {
+		qk   Quadkey
+		vals []interface{}
+	}{
+		{"0231010", nil},
+		{"02310103", nil},
+		{"0231012", nil},
+		{"0231011", []interface{}{"0231011", "02310110"}},
+		{"023102", []interface{}{"0231020"}},
+	}
+	errf := "Values(%s) -> %v"
+	for _, test := range tests {
+		if vals := idx.Values(test.qk.ToTile()); !reflect.DeepEqual(vals, test.vals) {
+			t.Errorf(errf, test.qk, vals)
+		}
+	}
+}
+
+func TestKeysetIndexValuesOrder(t *testing.T) {
+	nyc := Tile{X: 75, Y: 96, Z: 8}
+	esb := FromCoordinate(40.7484, -73.9857, 18)
+	sol := FromCoordinate(40.6892, -74.0445, 18)
+	entries := []Entry{{esb, "EmpireStateBuilding"}, {nyc, "NewYork"}, {sol, "StatueOfLiberty"}, {esb, "ChryslerBuilding"}, {nyc, "Manhattan"}}
+	exp := []interface{}{"NewYork", "Manhattan", "EmpireStateBuilding", "ChryslerBuilding", "StatueOfLiberty"}
+	for i := 0; i < 20; i++ {
+		idx := &KeysetIndex{}
+		for j := 0; j < 200; j++ {
+			idx.Add(FromCoordinate(51.5007, -0.1246, 18), j)
+		}
+		for _, e := range entries {
+			idx.Add(e.Tile, e.Value)
+		}
+		rand.Shuffle(len(idx.keys), func(i, j int) { idx.keys[i], idx.keys[j] = idx.keys[j], idx.keys[i] })
+		if vals := idx.Values(nyc); !reflect.DeepEqual(vals, exp) {
+			t.Fatal("Values NYC not in quadkey then insertion order: ", vals)
+		}
+		for j, v := range idx.Values(FromCoordinate(51.5007, -0.1246, 18)) {
+			if v != j {
+				t.Fatalf("Values at %d -> %v, not in insertion order within a quadkey", j, v)
+			}
+		}
+	}
+}
+
+func TestKeysetIndexValuesByQuadkey(t *testing.T) {
+	idx := &KeysetIndex{}
+	testIndex(t, idx)
+	tests := []struct {
+		qk  string
+		n   int
+		err bool
+	}{
+		{"", 3, false},
+		{"0320", 2, false},
+		{string(FromCoordinate(40.7484, -73.9857, 18).Quadkey()), 1, false},
+		{"0123", 0, false},
+		{"0324", 0, true},
+		{"03a", 0, true},
+		{"012301230123012301230123", 0, true},
+	}
+	errf := "ValuesByQuadkey(%q) -> %v, %v"
+	for _, test := range tests {
+		vals, err := idx.ValuesByQuadkey(test.qk)
+		if len(vals) != test.n || (err != nil) != test.err {
+			t.Errorf(errf, test.qk, vals, err)
+		}
+		if err == nil && !reflect.DeepEqual(vals, idx.Values(Quadkey(test.qk).ToTile())) {
+			t.Errorf(errf, test.qk, vals, err)
+		}
+	}
+}
+
+func TestKeysetIndexValuesExact(t *testing.T) {
+	idx := &KeysetIndex{}
+	nyc := Tile{X: 75, Y: 96, Z: 8}
+	idx.Add(FromCoordinate(40.7484, -73.9857, 18), "EmpireStateBuilding")
+	idx.Add(nyc, "NewYork")
+	idx.Add(FromCoordinate(40.6892, -74.0445, 18), "StatueOfLiberty")
+	idx.Add(nyc, "Manhattan")
+	if vals := idx.ValuesExact(nyc); !reflect.DeepEqual(vals, []interface{}{"NewYork", "Manhattan"}) {
+		t.Error("ValuesExact NYC: ", vals)
+	}
+	if vals := idx.ValuesExact(nyc.Quadkey().Parent(7).ToTile()); len(vals) != 0 {
+		t.Error("ValuesExact NYC parent: ", vals)
+	}
+	if n := len(idx.Values(nyc)); n != 4 {
+		t.Error("Values NYC expected 4 values, got ", n)
+	}
+}
+
+func TestKeysetIndexCoverage(t *testing.T) {
+	idx := &KeysetIndex{}
+	// the bbox is covered by 301/384 and 301/385 at zoom 10
+	minLat, minLon, maxLat, maxLon := 40.6, -74.1, 40.8, -73.9
+	if c := idx.Coverage(minLat, minLon, maxLat, maxLon, 10); c != 0 {
+		t.Errorf("Coverage on an empty index -> %v", c)
+	}
+	idx.Add(FromCoordinate(40.7484, -73.9857, 18), "EmpireStateBuilding")
+	idx.Add(FromCoordinate(51.5007, -0.1246, 18), "BigBen")
+	idx.Add(Tile{X: 301, Y: 385, Z: 10})
+	tests := []struct {
+		zoom     int
+		coverage float64
+	}{
+		{10, 0.5},
+		{8, 1},
+		{18, 1 / float64(len(TilesForBBox(minLat, minLon, maxLat, maxLon, 18)))},
+	}
+	errf := "Coverage(%v, %v, %v, %v, %d) -> %v"
+	for _, test := range tests {
+		if c := idx.Coverage(minLat, minLon, maxLat, maxLon, test.zoom); c != test.coverage {
+			t.Errorf(errf, minLat, minLon, maxLat, maxLon, test.zoom, c)
+		}
+	}
+	idx.Add(FromCoordinate(40.6892, -74.0445, 18), "StatueOfLiberty")
+	if c := idx.Coverage(minLat, minLon, maxLat, maxLon, 10); c != 1 {
+		t.Errorf(errf, minLat, minLon, maxLat, maxLon, 10, c)
+	}
+}
+
+func TestKeysetIndexBounds(t *testing.T) {
+	idx := &KeysetIndex{}
+	hydrateIndexN(idx, 1000)
+	idx.Add(FromCoordinate(51.5007, -0.1246, 18), "BigBen")
+	idx.Add(Tile{}, "World")
+	esb := FromCoordinate(40.7484, -73.9857, 18).Quadkey()
+	errf := "KeysetIndex bounds %q -> [%d, %d) %v"
+	for _, qk := range []Quadkey{"", "0", "03", "0320", esb[:8], esb[:12], esb, "1", "3"} {
+		lo, err := idx.LowerBound(string(qk))
+		check(err)
+		hi, err := idx.UpperBound(string(qk))
+		check(err)
+		var vals []interface{}
+		err = idx.RangeFunc(string(qk), func(k Quadkey, v []interface{}) bool {
+			if !k.HasParent(qk) && k != qk {
+				t.Errorf(errf, qk, lo, hi, k)
+			}
+			vals = append(vals, v...)
+			return true
+		})
+		check(err)
+		if exp, _ := idx.ValuesByQuadkey(string(qk)); !reflect.DeepEqual(vals, exp) || hi-lo != idx.Count(qk.ToTile()) {
+			t.Errorf(errf, qk, lo, hi, vals)
+		}
+	}
+	calls := 0
+	check(idx.RangeFunc("", func(Quadkey, []interface{}) bool {
+		calls++
+		return calls < 3
+	}))
+	if calls != 3 {
+		t.Error("RangeFunc didn't stop early: ", calls)
+	}
+	for _, qk := range []string{"4", "000000000000000000000000"} {
+		if _, err := idx.LowerBound(qk); err == nil {
+			t.Errorf("LowerBound(%q) expected an error", qk)
+		}
+		if _, err := idx.UpperBound(qk); err == nil {
+			t.Errorf("UpperBound(%q) expected an error", qk)
+		}
+		if err := idx.RangeFunc(qk, nil); err == nil {
+			t.Errorf("RangeFunc(%q) expected an error", qk)
+		}
+	}
+}
+
+func TestKeysetIndexValuesCopy(t *testing.T) {
+	idx := &KeysetIndex{}
+	nyc := Tile{X: 75, Y: 96, Z: 8}
+	esb := &landmark{"EmpireStateBuilding", 443}
+	idx.Add(FromCoordinate(40.7484, -73.9857, 18), esb)
+	idx.Add(FromCoordinate(40.6892, -74.0445, 18), &landmark{"StatueOfLiberty", 93})
+	clone := func(val interface{}) interface{} {
+		l := *val.(*landmark)
+		return &l
+	}
+	vals := idx.ValuesCopy(nyc, clone)
+	shared := idx.Values(nyc)
+	esb.Height = 381
+	if len(vals) != 2 || *vals[0].(*landmark) != (landmark{"EmpireStateBuilding", 443}) || *vals[1].(*landmark) != (landmark{"StatueOfLiberty", 93}) {
+		t.Error("ValuesCopy saw a later change: ", vals)
+	}
+	if shared[0].(*landmark).Height != 381 {
+		t.Error("Values didn't share the stored reference: ", shared)
+	}
+	if vals := idx.ValuesCopy(Tile{X: 106, Y: 194, Z: 9}, clone); vals != nil {
+		t.Error("ValuesCopy on an empty tile: ", vals)
+	}
+}
+
+func TestKeysetIndexValuesSorted(t *testing.T) {
+	idx := &KeysetIndex{}
+	nyc := Tile{X: 75, Y: 96, Z: 8}
+	idx.Add(FromCoordinate(40.7484, -73.9857, 18), landmark{"EmpireStateBuilding", 443}, landmark{"ChryslerBuilding", 319})
+	idx.Add(FromCoordinate(40.6892, -74.0445, 18), landmark{"StatueOfLiberty", 93})
+	idx.Add(nyc, landmark{"NewYork", 0}, landmark{"Manhattan", 0})
+	taller := func(a, b interface{}) bool {
+		return a.(landmark).Height > b.(landmark).Height
+	}
+	exp := []interface{}{
+		landmark{"EmpireStateBuilding", 443},
+		landmark{"ChryslerBuilding", 319},
+		landmark{"StatueOfLiberty", 93},
+		landmark{"NewYork", 0},
+		landmark{"Manhattan", 0},
+	}
+	if vals := idx.ValuesSorted(nyc, taller); !reflect.DeepEqual(vals, exp) {
+		t.Errorf("ValuesSorted(%v) -> %v", nyc, vals)
+	}
+	if vals := idx.ValuesSorted(Tile{X: 106, Y: 194, Z: 9}, taller); len(vals) != 0 {
+		t.Error("ValuesSorted on an empty tile: ", vals)
+	}
+}
+
+func TestKeysetIndexValuesLimited(t *testing.T) {
+	idx := &KeysetIndex{}
+	nyc := Tile{X: 75, Y: 96, Z: 8}
+	idx.Add(FromCoordinate(40.7484, -73.9857, 18), "EmpireStateBuilding")
+	idx.Add(nyc, "NewYork", "Manhattan")
+	idx.Add(FromCoordinate(40.6892, -74.0445, 18), "StatueOfLiberty")
+	all := idx.Values(nyc)
+	tests := []struct {
+		max       int
+		vals      []interface{}
+		truncated bool
+	}{
+		{10, all, false},
+		{4, all, false},
+		{3, all[:3], true},
+		{1, all[:1], true},
+		{0, nil, true},
+		{-1, nil, true},
+	}
+	errf := "ValuesLimited(%v, %d) -> %v, %t"
+	for _, test := range tests {
+		vals, truncated := idx.ValuesLimited(nyc, test.max)
+		if !reflect.DeepEqual(vals, test.vals) || truncated != test.truncated {
+			t.Errorf(errf, nyc, test.max, vals, truncated)
+		}
+	}
+	if vals, truncated := idx.ValuesLimited(Tile{X: 106, Y: 194, Z: 9}, 0); vals != nil || truncated {
+		t.Errorf(errf, Tile{X: 106, Y: 194, Z: 9}, 0, vals, truncated)
+	}
+}
+
+func TestKeysetIndexValuesPage(t *testing.T) {
+	idx := &KeysetIndex{}
+	nyc := Tile{X: 75, Y: 96, Z: 8}
+	idx.Add(FromCoordinate(40.7484, -73.9857, 18), "EmpireStateBuilding")
+	idx.Add(nyc, "NewYork", "Manhattan")
+	idx.Add(FromCoordinate(40.6892, -74.0445, 18), "StatueOfLiberty")
+	idx.Add(FromCoordinate(51.5007, -0.1246, 18), "BigBen")
+	all := idx.Values(nyc)
+	tests := []struct {
+		offset, limit int
+		vals          []interface{}
+		more          bool
+	}{
+		{0, 10, all, false},
+		{0, 4, all, false},
+		{0, 3, all[:3], true},
+		{0, 2, all[:2], true},
+		{1, 2, all[1:3], true},
+		{2, 2, all[2:], false},
+		{3, 1, all[3:], false},
+		{4, 1, nil, false},
+		{0, 0, nil, true},
+		{-1, 2, all[:2], true},
+		{0, -1, nil, true},
+		{-3, -3, nil, true},
+		{5, -1, nil, false},
+	}
+	errf := "ValuesPage(%v, %d, %d) -> %v, %t"
+	for _, test := range tests {
+		vals, more := idx.ValuesPage(nyc, test.offset, test.limit)
+		if !reflect.DeepEqual(vals, test.vals) || more != test.more {
+			t.Errorf(errf, nyc, test.offset, test.limit, vals, more)
+		}
+	}
+	// paging through the whole tile returns the same values as Values
+	var paged []interface{}
+	for offset, more := 0, true; more; offset += 3 {
+		var vals []interface{}
+		vals, more = idx.ValuesPage(Tile{}, offset, 3)
+		paged = append(paged, vals...)
+	}
+	if vals := idx.Values(Tile{}); !reflect.DeepEqual(paged, vals) {
+		t.Errorf("ValuesPage pages %v != Values %v", paged, vals)
+	}
+}
+
+func TestKeysetIndexValuesMulti(t *testing.T) {
+	idx := &KeysetIndex{}
+	esb := FromCoordinate(40.7484, -73.9857, 18)
+	sol := FromCoordinate(40.6892, -74.0445, 18)
+	bbn := FromCoordinate(51.5007, -0.1246, 18)
+	nyc := Tile{X: 75, Y: 96, Z: 8}
+	den := Tile{X: 106, Y: 194, Z: 9}
+	idx.Add(esb, "EmpireStateBuilding")
+	idx.Add(sol, "StatueOfLiberty")
+	idx.Add(bbn, "BigBen")
+	idx.Add(nyc, "NewYork")
+	tests := [][]Tile{
+		nil,
+		{esb},
+		{bbn, esb, sol},
+		{sol, nyc, den, Tile{}},
+		{esb, esb, nyc.Parent(), nyc, nyc.Children()[0]},
+	}
+	errf := "ValuesMulti(%v) -> %v"
+	for _, tiles := range tests {
+		vals := idx.ValuesMulti(tiles)
+		for _, tile := range tiles {
+			if v, ok := vals[tile]; !ok || !reflect.DeepEqual(v, idx.Values(tile)) {
+				t.Errorf(errf, tiles, vals)
+			}
+		}
+		if len(vals) > len(tiles) {
+			t.Errorf(errf, tiles, vals)
+		}
+	}
+}
+
+func TestKeysetIndexValuesInBBox(t *testing.T) {
+	idx := &KeysetIndex{}
+	idx.Add(FromCoordinate(40.7484, -73.9857, 18), "EmpireStateBuilding")
+	idx.Add(FromCoordinate(40.6892, -74.0445, 18), "StatueOfLiberty")
+	idx.Add(FromCoordinate(51.5007, -0.1246, 18), "BigBen")
+	idx.Add(FromCoordinate(-18.1416, 178.4419, 18), "Suva")
+	idx.Add(FromCoordinate(-13.8333, -171.7500, 18), "Apia")
+	tests := []struct {
+		minLat, minLon, maxLat, maxLon float64
+		zoom                           int
+		n                              int
+	}{
+		{40.7, -74.0, 40.8, -73.9, 14, 1},
+		{40.6, -74.1, 40.8, -73.9, 12, 2},
+		{40.6, -74.1, 52, 0, 4, 3},
+		{-20, 170, -10, -170, 8, 2},
+		{-20, 170, -10, 179, 8, 1},
+		{0, 0, 10, 10, 10, 0},
+	}
+	errf := "ValuesInBBox(%v, %v, %v, %v, %d) -> %v"
+	for _, test := range tests {
+		vals := idx.ValuesInBBox(test.minLat, test.minLon, test.maxLat, test.maxLon, test.zoom)
+		if len(vals) != test.n {
+			t.Errorf(errf, test.minLat, test.minLon, test.maxLat, test.maxLon, test.zoom, vals)
+		}
+	}
+}
+
+func TestKeysetIndexMapValues(t *testing.T) {
+	idx := &KeysetIndex{}
+	esb := FromCoordinate(40.7484, -73.9857, 18)
+	vals := []interface{}{1, 2}
+	idx.Add(esb, vals...)
+	idx.Add(FromCoordinate(40.6892, -74.0445, 18), 3)
+	idx.Values(esb)
+	idx.MapValues(func(val interface{}) interface{} {
+		return val.(int) * 10
+	})
+	if !idx.sorted {
+		t.Error("MapValues unsorted the index")
+	}
+	if v := idx.Values(Tile{X: 75, Y: 96, Z: 8}); !reflect.DeepEqual(v, []interface{}{10, 20, 30}) {
+		t.Error("MapValues NYC: ", v)
+	}
+	if vals[0] != 1 {
+		t.Error("MapValues modified the added slice ", vals)
+	}
+}
+
+func TestKeysetIndexFilter(t *testing.T) {
+	idx := &KeysetIndex{}
+	esb := FromCoordinate(40.7484, -73.9857, 18)
+	sol := FromCoordinate(40.6892, -74.0445, 18)
+	bbn := FromCoordinate(51.5007, -0.1246, 18)
+	idx.Add(bbn, 1)
+	idx.Add(esb, 2, 3, 4)
+	idx.Add(sol, 5)
+	idx.Add(esb, 6)
+	idx.Values(esb)
+	n := idx.Filter(func(val interface{}) bool {
+		return val.(int)%2 == 0
+	})
+	if n != 3 {
+		t.Error("Filter expected to remove 3 values, got ", n)
+	}
+	if !idx.sorted {
+		t.Error("Filter unsorted the index")
+	}
+	if v := idx.Values(Tile{}); !reflect.DeepEqual(v, []interface{}{2, 4, 6}) {
+		t.Error("Filter values: ", v)
+	}
+	if len(idx.keys) != 2 || len(idx.values) != 2 {
+		t.Errorf("Filter did not compact keys %d and values %d", len(idx.keys), len(idx.values))
+	}
+	idx.Add(sol, 8)
+	if v := idx.Values(Tile{X: 75, Y: 96, Z: 8}); len(v) != 4 {
+		t.Error("Add after Filter NYC: ", v)
+	}
+}
+
+func TestPkey(t *testing.T) {
+	qks := []Quadkey{"", "0", "00", "0123", "013", "1", "2", "3", "30", "3333", "0231010301", "03201011013202332123333"}
+	for _, qk := range qks {
+		k, err := packQuadkey(qk)
+		if err != nil {
+			t.Fatalf("packQuadkey(%q) -> %v", qk, err)
+		}
+		if k != packTile(qk.ToTile()) {
+			t.Errorf("packQuadkey(%q) != packTile(%+v)", qk, qk.ToTile())
+		}
+		if k.Quadkey() != qk || k.Level() != qk.Level() || k.ToTile() != qk.ToTile() {
+			t.Errorf("pkey(%q) -> %q, %d, %+v", qk, k.Quadkey(), k.Level(), k.ToTile())
+		}
+		for z := 0; z <= qk.Level(); z++ {
+			if k.Parent(z).Quadkey() != qk.Parent(z) {
+				t.Errorf("pkey(%q).Parent(%d) -> %q", qk, z, k.Parent(z).Quadkey())
+			}
+		}
+		for _, o := range qks {
+			ok, _ := packQuadkey(o)
+			if (k < ok) != (qk < o) {
+				t.Errorf("pkey(%q) < pkey(%q) -> %v", qk, o, k < ok)
+			}
+			if k.HasParent(ok) != qk.HasParent(o) {
+				t.Errorf("pkey(%q).HasParent(%q) -> %v", qk, o, k.HasParent(ok))
+			}
+		}
+	}
+	for _, qk := range []Quadkey{"4", "01a", "032010110132023321233330"} {
+		if _, err := packQuadkey(qk); err == nil {
+			t.Errorf("packQuadkey(%q) did not error", qk)
+		}
+	}
+}
+
+func TestKeysetIndexCompact(t *testing.T) {
+	idx := &KeysetIndex{}
+	esb := FromCoordinate(40.7484, -73.9857, 18)
+	sol := FromCoordinate(40.6892, -74.0445, 18)
+	idx.Add(esb, landmark{"EmpireStateBuilding", 443}, landmark{"EmpireStateBuilding", 443})
+	idx.Add(sol, "StatueOfLiberty")
+	idx.Add(esb, landmark{"EmpireStateBuilding", 443}, "ChryslerBuilding")
+	idx.Add(sol, "StatueOfLiberty")
+	idx.Add(esb.Parent(), "Midtown")
+	if n := idx.Compact(); n != 3 {
+		t.Error("Compact expected to remove 3 values, got ", n)
+	}
+	if len(idx.keys) != 3 || len(idx.values) != 3 {
+		t.Errorf("Compact left keys %d and values %d", len(idx.keys), len(idx.values))
+	}
+	exp := []interface{}{"Midtown", landmark{"EmpireStateBuilding", 443}, "ChryslerBuilding", "StatueOfLiberty"}
+	if v := idx.Values(Tile{X: 75, Y: 96, Z: 8}); !reflect.DeepEqual(v, exp) {
+		t.Error("Compact NYC: ", v)
+	}
+	if n := idx.Compact(); n != 0 {
+		t.Error("Compact on a compacted index removed ", n)
+	}
+}
+
+func TestKeysetIndexShrink(t *testing.T) {
+	idx := &KeysetIndex{}
+	esb := FromCoordinate(40.7484, -73.9857, 18)
+	sol := FromCoordinate(40.6892, -74.0445, 18)
+	for i := 0; i < 1000; i++ {
+		idx.Add(esb, i)
+	}
+	idx.Add(sol)
+	idx.Upsert(sol, "ferry", "Ferry@SOL")
+	for i := 0; i < 998; i++ {
+		idx.Delete(esb, i)
+	}
+	if n := idx.Shrink(); n <= 0 {
+		t.Error("Shrink after deletes reclaimed ", n)
+	}
+	if len(idx.keys) != 3 || cap(idx.keys) != 3 || cap(idx.values) != 3 {
+		t.Errorf("Shrink left keys %d/%d and values %d", len(idx.keys), cap(idx.keys), cap(idx.values))
+	}
+	exp := []interface{}{998, 999, "Ferry@SOL"}
+	if v := idx.Values(Tile{X: 75, Y: 96, Z: 8}); !reflect.DeepEqual(v, exp) {
+		t.Error("Shrink NYC: ", v)
+	}
+	idx.Upsert(esb, "ferry", "Ferry@ESB")
+	if v := idx.Values(sol); len(v) != 0 {
+		t.Error("Upsert after Shrink SOL: ", v)
+	}
+	idx.Shrink()
+	if n := idx.Shrink(); n != 0 {
+		t.Error("Shrink on a shrunk index reclaimed ", n)
+	}
+}
+
+func TestKeysetIndexUpsert(t *testing.T) {
+	idx := &KeysetIndex{}
+	esb := FromCoordinate(40.7484, -73.9857, 18)
+	sol := FromCoordinate(40.6892, -74.0445, 18)
+	bbn := FromCoordinate(51.5007, -0.1246, 18)
+	nyc := Tile{X: 75, Y: 96, Z: 8}
+	idx.Add(esb, "EmpireStateBuilding")
+	idx.Upsert(sol, "ferry", "Ferry@SOL")
+	idx.Upsert(bbn, "plane", "Plane@BBN")
+	if v := idx.Values(nyc); !reflect.DeepEqual(v, []interface{}{"EmpireStateBuilding", "Ferry@SOL"}) {
+		t.Error("Upsert NYC: ", v)
+	}
+	idx.Upsert(sol, "ferry", "Ferry@SOL2")
+	if v := idx.Values(sol); !reflect.DeepEqual(v, []interface{}{"Ferry@SOL2"}) {
+		t.Error("Upsert same tile SOL: ", v)
+	}
+	idx.Upsert(esb, "plane", "Plane@ESB")
+	if v := idx.Values(bbn); len(v) != 0 {
+		t.Error("Upsert moved BBN: ", v)
+	}
+	if v := idx.Values(esb); !reflect.DeepEqual(v, []interface{}{"EmpireStateBuilding", "Plane@ESB"}) {
+		t.Error("Upsert moved ESB: ", v)
+	}
+	idx.Add(esb, "EmpireStateBuilding")
+	idx.Compact()
+	idx.Upsert(esb, "ferry", "Ferry@ESB")
+	if v := idx.Values(nyc); len(v) != 3 || idx.Len() != 3 {
+		t.Error("Upsert after Compact NYC: ", v)
+	}
+	idx.Delete(esb, "EmpireStateBuilding")
+	idx.Upsert(bbn, "ferry", "Ferry@BBN")
+	idx.Upsert(bbn, "plane", "Plane@BBN")
+	if v := idx.Values(nyc); len(v) != 0 {
+		t.Error("Upsert after Delete NYC: ", v)
+	}
+	idx.Filter(func(val interface{}) bool { return val != "Ferry@BBN" })
+	idx.Upsert(sol, "ferry", "Ferry@SOL")
+	if v := idx.Values(Tile{}); !reflect.DeepEqual(v, []interface{}{"Plane@BBN", "Ferry@SOL"}) {
+		t.Error("Upsert after Filter: ", v)
+	}
+}
+
+func TestKeysetIndexTilesOf(t *testing.T) {
+	idx := &KeysetIndex{}
+	esb := FromCoordinate(40.7484, -73.9857, 18)
+	sol := FromCoordinate(40.6892, -74.0445, 18)
+	bbn := FromCoordinate(51.5007, -0.1246, 18)
+	idx.Add(sol, landmark{"Ferry", 10})
+	idx.Add(esb, "EmpireStateBuilding", landmark{"Ferry", 10})
+	idx.Add(bbn, landmark{"Ferry", 12})
+	idx.Add(sol, landmark{"Ferry", 10})
+	if tiles := idx.TilesOf(landmark{"Ferry", 10}); !reflect.DeepEqual(tiles, []Tile{esb, sol}) {
+		t.Error("TilesOf Ferry: ", tiles)
+	}
+	if tiles := idx.TilesOf("BigBen"); len(tiles) != 0 {
+		t.Error("TilesOf BigBen: ", tiles)
+	}
+}
+
+func TestKeysetIndexSnapshot(t *testing.T) {
+	idx := &KeysetIndex{}
+	esb := FromCoordinate(40.7484, -73.9857, 18)
+	sol := FromCoordinate(40.6892, -74.0445, 18)
+	nyc := Tile{X: 75, Y: 96, Z: 8}
+	idx.Add(esb, "EmpireStateBuilding")
+	idx.Upsert(sol, "ferry", "Ferry")
+	snap := idx.Snapshot()
+	if !snap.sorted {
+		t.Error("Snapshot is not sorted")
+	}
+	idx.Add(sol, "StatueOfLiberty")
+	idx.Delete(esb, "EmpireStateBuilding")
+	idx.Upsert(esb, "ferry", "Ferry")
+	if v := snap.Values(nyc); !reflect.DeepEqual(v, []interface{}{"EmpireStateBuilding", "Ferry"}) {
+		t.Error("Snapshot NYC: ", v)
+	}
+	snap.Upsert(esb, "ferry", "Ferry")
+	if v := idx.Values(nyc); !reflect.DeepEqual(v, []interface{}{"Ferry", "StatueOfLiberty"}) {
+		t.Error("Index NYC: ", v)
+	}
+	// the snapshot enforces the same limits as the index
+	capped := NewKeysetIndexMaxZoom(0, 8).Snapshot()
+	capped.Add(esb, "EmpireStateBuilding")
+	if v := capped.ValuesExact(nyc); !reflect.DeepEqual(v, []interface{}{"EmpireStateBuilding"}) {
+		t.Error("Snapshot of a max zoom index didn't truncate: ", v)
+	}
+	leaves := NewKeysetIndexLeaves(0)
+	leaves.Add(esb, "EmpireStateBuilding")
+	if err := leaves.Snapshot().TryAdd(nyc, "NewYork"); err == nil {
+		t.Error("Snapshot of a leaves index took an ancestor")
+	}
+	if !NewKeysetIndexExpiring(0).Snapshot().stamped {
+		t.Error("Snapshot of an expiring index isn't stamped")
+	}
+}
+
+func TestKeysetIndexPruneBelow(t *testing.T) {
+	idx := &KeysetIndex{}
+	hydrateIndexN(idx, 1000)
+	esb := FromCoordinate(40.7484, -73.9857, 18)
+	idx.Upsert(esb, "esb", "EmpireStateBuilding")
+	idx.Add(Tile{X: 1, Y: 1, Z: 2}, "Shallow")
+	before := idx.Densities(12)
+	idx.PruneBelow(12)
+	if s := idx.Stats(); s.MaxZoom != 12 || s.MinZoom != 2 || s.Values != 1002 {
+		t.Error("PruneBelow stats: ", s)
+	}
+	if !sort.IsSorted(byQk(idx.keys)) {
+		t.Error("PruneBelow unsorted the keys")
+	}
+	if after := idx.Densities(12); !reflect.DeepEqual(before, after) {
+		t.Error("PruneBelow changed densities at zoom 12")
+	}
+	if v := idx.ValuesExact(esb.Quadkey().Parent(12).ToTile()); len(v) == 0 {
+		t.Error("PruneBelow did not move ESB to zoom 12")
+	}
+	idx.Upsert(esb.Parent(), "esb", "EmpireStateBuilding")
+	if n := idx.Count(esb.Quadkey().Parent(12).ToTile()); n != len(idx.Values(esb.Quadkey().Parent(12).ToTile())) {
+		t.Error("PruneBelow Upsert count mismatch ", n)
+	}
+	if n := idx.Len(); n != 1002 {
+		t.Error("PruneBelow then Upsert expected 1002 values, got ", n)
+	}
+}
+
+func TestKeysetIndexLoadFrom(t *testing.T) {
+	idx := &KeysetIndex{}
+	ch := make(chan Entry)
+	go func() {
+		defer close(ch)
+		for i := 0; i < 5000; i++ {
+			ch <- Entry{Tile{X: i, Y: i, Z: 18}, i}
+		}
+	}()
+	if err := idx.LoadFrom(context.Background(), ch); err != nil {
+		t.Error("LoadFrom -> ", err)
+	}
+	if !idx.sorted || idx.Len() != 5000 {
+		t.Errorf("LoadFrom sorted %v, %d values", idx.sorted, idx.Len())
+	}
+	if v := idx.Values(Tile{X: 42, Y: 42, Z: 18}); !reflect.DeepEqual(v, []interface{}{42}) {
+		t.Error("LoadFrom 42: ", v)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	ch = make(chan Entry, 1)
+	ch <- Entry{Tile{X: 1, Y: 1, Z: 1}, "cancelled"}
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		cancel()
+	}()
+	if err := idx.LoadFrom(ctx, ch); err != context.Canceled {
+		t.Error("LoadFrom cancelled -> ", err)
+	}
+	if !idx.sorted || idx.Len() != 5001 {
+		t.Errorf("LoadFrom cancelled sorted %v, %d values", idx.sorted, idx.Len())
+	}
+}
+
+func TestKeysetIndexAddQuadkey(t *testing.T) {
+	idx := &KeysetIndex{}
+	esb := FromCoordinate(40.7484, -73.9857, 18)
+	for _, qk := range []string{string(esb.Quadkey()), "", "0320"} {
+		if err := idx.AddQuadkey(qk, qk); err != nil {
+			t.Errorf("KeysetIndex.AddQuadkey(%q) -> %v", qk, err)
+		}
+	}
+	for _, qk := range []string{"4", "03a", "000000000000000000000000"} {
+		if err := idx.AddQuadkey(qk, qk); err == nil {
+			t.Errorf("KeysetIndex.AddQuadkey(%q) expected an error", qk)
+		}
+	}
+	if v := idx.Values(esb); !reflect.DeepEqual(v, []interface{}{string(esb.Quadkey())}) {
+		t.Error("AddQuadkey ESB: ", v)
+	}
+	if n := idx.Len(); n != 3 {
+		t.Error("AddQuadkey added ", n)
+	}
+}
+
+func TestKeysetIndexAddPoint(t *testing.T) {
+	pointTests := []struct {
+		lat, lon, meters float64
+		tile             Tile
+	}{
+		{40.7484, -73.9857, 100, Tile{X: 77197, Y: 98526, Z: 18}},
+		{40.7484, -73.9857, 0, TileFromLatLng(40.7484, -73.9857, ZMax)},
+		{40.7484, -73.9857, 1e9, Tile{}},
+		{40.7484, 286.0143, 100, Tile{X: 77197, Y: 98526, Z: 18}},
+	}
+	errf := "KeysetIndex.AddPoint(%v, %v, %v) -> %v"
+	for _, test := range pointTests {
+		idx := NewKeysetIndex(1)
+		idx.AddPoint(test.lat, test.lon, test.meters, "val")
+		var tiles []Tile
+		for qk := range idx.All() {
+			tiles = append(tiles, qk.ToTile())
+		}
+		if len(tiles) != 1 || tiles[0] != test.tile {
+			t.Errorf(errf, test.lat, test.lon, test.meters, tiles)
+		}
+	}
+}
+
+func TestKeysetIndexGet(t *testing.T) {
+	idx := &KeysetIndex{}
+	esb := FromCoordinate(40.7484, -73.9857, 18)
+	sol := FromCoordinate(40.6892, -74.0445, 18)
+	idx.Add(esb, "EmpireStateBuilding")
+	idx.Upsert(sol, "ferry", "Ferry@SOL")
+	idx.Upsert(esb, "plane", "Plane@ESB")
+	idx.Upsert(esb, "plane", "Plane@ESB2")
+	tests := []struct {
+		tile Tile
+		id   string
+		val  interface{}
+		ok   bool
+	}{
+		{sol, "ferry", "Ferry@SOL", true},
+		{esb, "plane", "Plane@ESB2", true},
+		{esb, "ferry", nil, false},
+		{Tile{X: 75, Y: 96, Z: 8}, "ferry", nil, false},
+		{esb, "EmpireStateBuilding", nil, false},
+	}
+	errf := "KeysetIndex.Get(%v, %q) -> %v, %t"
+	for _, test := range tests {
+		if val, ok := idx.Get(test.tile, test.id); val != test.val || ok != test.ok {
+			t.Errorf(errf, test.tile, test.id, val, ok)
+		}
+	}
+	idx.Delete(sol, "Ferry@SOL")
+	if val, ok := idx.Get(sol, "ferry"); ok {
+		t.Errorf(errf, sol, "ferry", val, ok)
+	}
+}
+
+func TestKeysetIndexSortedKeys(t *testing.T) {
+	idx := &KeysetIndex{}
+	if qks := idx.SortedKeys(); len(qks) != 0 {
+		t.Error("SortedKeys on an empty index: ", qks)
+	}
+	esb := FromCoordinate(40.7484, -73.9857, 18)
+	sol := FromCoordinate(40.6892, -74.0445, 18)
+	nyc := Tile{X: 75, Y: 96, Z: 8}
+	idx.Add(sol, "StatueOfLiberty")
+	idx.Add(esb, "EmpireStateBuilding")
+	idx.Add(nyc, "NewYork")
+	idx.Add(esb, "ChryslerBuilding")
+	exp := []string{string(nyc.Quadkey()), string(esb.Quadkey()), string(esb.Quadkey()), string(sol.Quadkey())}
+	qks := idx.SortedKeys()
+	if !reflect.DeepEqual(qks, exp) {
+		t.Error("SortedKeys -> ", qks)
+	}
+	qks[0] = ""
+	if idx.SortedKeys()[0] != exp[0] {
+		t.Error("SortedKeys returned the keyset instead of a copy")
+	}
+}
+
 func TestTileIndex(t *testing.T) {
 	idx := NewTileIndex()
 	testIndex(t, idx)
@@ -44,6 +1634,14 @@ func TestKeysetIndex(t *testing.T) {
 	testIndex(t, TileIndex(idx))
 }
 
+func TestNewKeysetIndex(t *testing.T) {
+	idx := NewKeysetIndex(1 << 10)
+	if cap(idx.keys) != 1<<10 || cap(idx.values) != 1<<10 {
+		t.Errorf("NewKeysetIndex(%d) -> keys cap %d, values cap %d", 1<<10, cap(idx.keys), cap(idx.values))
+	}
+	testIndex(t, TileIndex(idx))
+}
+
 func TestSuffixIndex(t *testing.T) {
 	idx := NewSuffixIndex()
 	testIndex(t, TileIndex(idx))
@@ -111,9 +1709,36 @@ func BenchmarkSuffixValues(b *testing.B) {
 	}
 }
 
+func BenchmarkKeysetMemory(b *testing.B) {
+	const n = 5000000
+	tiles := make([]Tile, n)
+	for i := range tiles {
+		tiles[i] = Tile{X: rand.Intn(1 << 18), Y: rand.Intn(1 << 18), Z: 18}
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		var before, after runtime.MemStats
+		runtime.GC()
+		runtime.ReadMemStats(&before)
+		idx := NewKeysetIndex(n)
+		for _, t := range tiles {
+			idx.Add(t)
+		}
+		idx.sort()
+		runtime.GC()
+		runtime.ReadMemStats(&after)
+		b.ReportMetric(float64(after.HeapAlloc-before.HeapAlloc)/n, "B/key")
+		runtime.KeepAlive(idx)
+	}
+}
+
 func hydrateIndex(idx TileIndex) {
+	hydrateIndexN(idx, 10000)
+}
+
+func hydrateIndexN(idx TileIndex, n int) {
 	mlat, mlon := 40.7, -73.9
-	for i := 0; i < 10000; i++ {
+	for i := 0; i < n; i++ {
 		lat := mlat + 0.1*rand.Float64()
 		lon := mlon - 0.1*rand.Float64()
 		t := FromCoordinate(lat, lon, 18)
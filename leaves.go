@@ -0,0 +1,61 @@
+package tiles
+
+import (
+	"errors"
+	"sort"
+)
+
+// NewKeysetIndexLeaves returns an empty KeysetIndex like NewKeysetIndex that only stores values at leaves,
+// rejecting a write whose tile is an ancestor or descendant of a tile that's already stored so the same data isn't indexed at two depths.
+// TryAdd and AddQuadkey return the rejection, while Add, AddSorted, AddPoint and Upsert panic with it, since they have no error to return.
+// An Upsert may move its id between an ancestor and a descendant, as its previous key doesn't count.
+// The check is a binary search per level, so keys are kept sorted as they're written.
+// Bulk writes such as AddBatch, LoadFrom, Merge, ReadFrom, UnmarshalJSON and Replay aren't checked
+func NewKeysetIndexLeaves(capacity int) *KeysetIndex {
+	idx := NewKeysetIndex(capacity)
+	idx.leaves = true
+	return idx
+}
+
+// leaf returns an error if the index only stores leaves and a key other than the one for value slot skip is a strict ancestor or descendant of qk.
+// Pass a negative skip to check against every key
+// Caller must hold the write lock
+func (idx *KeysetIndex) leaf(qk pkey, skip int) error {
+	if !idx.leaves {
+		return nil
+	}
+	if !idx.sorted {
+		sort.Sort(byQk(idx.keys))
+		idx.sorted = true
+	}
+	conflict := func(other pkey) error {
+		return errors.New("Quadkey " + string(qk.Quadkey()) + " overlaps stored quadkey " + string(other.Quadkey()))
+	}
+	for z := 0; z < qk.Level(); z++ {
+		p := qk.Parent(z)
+		for i := idx.search(p); i < len(idx.keys) && idx.keys[i].qk == p; i++ {
+			if idx.keys[i].v != skip {
+				return conflict(p)
+			}
+		}
+	}
+	var err error
+	idx.scan(qk, func(k qkey) bool {
+		if k.qk != qk && k.v != skip {
+			err = conflict(k.qk)
+		}
+		return err == nil
+	})
+	return err
+}
+
+// put adds the key to the keyset, inserting it in order if the index only stores leaves so the next check doesn't need a sort
+// Caller must hold the write lock
+func (idx *KeysetIndex) put(k qkey) {
+	if idx.leaves && idx.sorted {
+		idx.insert(k)
+		return
+	}
+	idx.keys = append(idx.keys, k)
+	idx.sorted = false
+}
@@ -0,0 +1,83 @@
+package tiles
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestKeysetIndexLeaves(t *testing.T) {
+	idx := NewKeysetIndexLeaves(0)
+	esb := FromCoordinate(40.7484, -73.9857, 18)
+	sol := FromCoordinate(40.6892, -74.0445, 18)
+	nyc := Tile{X: 75, Y: 96, Z: 8}
+	if err := idx.TryAdd(esb, "EmpireStateBuilding"); err != nil {
+		t.Fatal("TryAdd(esb) -> ", err)
+	}
+	idx.AddBatch([]Entry{{sol, "StatueOfLiberty"}})
+	if err := idx.TryAdd(esb, "ChryslerBuilding"); err != nil {
+		t.Error("TryAdd at a stored tile -> ", err)
+	}
+	tests := []struct {
+		tile Tile
+		errf string
+	}{
+		{nyc, "TryAdd at an ancestor didn't error %v"},
+		{Tile{}, "TryAdd at the root didn't error %v"},
+		{esb.Quadkey().Parent(17).ToTile(), "TryAdd at a parent didn't error %v"},
+	}
+	for _, test := range tests {
+		if err := idx.TryAdd(test.tile, "NewYork"); err == nil {
+			t.Errorf(test.errf, test.tile)
+		}
+	}
+	idx.Add(Tile{X: 1, Y: 1, Z: 1}, "Elsewhere")
+	if err := idx.TryAdd(Tile{X: 3, Y: 2, Z: 2}, "Within"); err == nil {
+		t.Error("TryAdd at a descendant didn't error")
+	}
+	if vals := idx.Values(nyc); !reflect.DeepEqual(vals, []interface{}{"EmpireStateBuilding", "ChryslerBuilding", "StatueOfLiberty"}) {
+		t.Error("Rejected TryAdd changed the index: ", vals)
+	}
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Error("Add at an ancestor didn't panic")
+			}
+		}()
+		idx.Add(nyc, "NewYork")
+	}()
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Error("AddSorted at an ancestor didn't panic")
+			}
+		}()
+		idx.AddSorted(nyc, "NewYork")
+	}()
+	// an upsert can move between depths since its previous key is replaced
+	idx.Upsert(Tile{X: 0, Y: 0, Z: 4}, "plane", "Plane")
+	idx.Upsert(Tile{X: 0, Y: 0, Z: 6}, "plane", "Plane")
+	idx.Upsert(Tile{X: 0, Y: 0, Z: 4}, "plane", "Plane")
+	if v, ok := idx.Get(Tile{X: 0, Y: 0, Z: 4}, "plane"); !ok || v != "Plane" {
+		t.Error("Upsert didn't move between depths: ", v, ok)
+	}
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Error("Upsert at an ancestor didn't panic")
+			}
+		}()
+		idx.Upsert(nyc, "ferry", "Ferry")
+	}()
+	if _, ok := idx.Get(nyc, "ferry"); ok {
+		t.Error("Rejected Upsert was stored")
+	}
+	if err := idx.AddQuadkey(string(nyc.Quadkey()), "NewYork"); err == nil {
+		t.Error("AddQuadkey at an ancestor didn't return an error")
+	}
+	if err := idx.AddQuadkey(string(esb.Quadkey()), "Macy's"); err != nil {
+		t.Error("AddQuadkey at a stored tile -> ", err)
+	}
+	if err := NewKeysetIndex(0).TryAdd(nyc, "NewYork"); err != nil {
+		t.Error("NewKeysetIndex TryAdd -> ", err)
+	}
+}
@@ -0,0 +1,178 @@
+package tiles
+
+import (
+	"math"
+	"sort"
+)
+
+// Nearest returns the stored value closest to the coordinate along with the tile it was added to.
+// Distance is measured to the center of each value's tile, so values are only as precise as their tiles.
+// The search starts with the tile containing the point at maxZoom and its neighbors, zooming out until a value is found.
+// Once found, the neighborhood one zoom out is also searched to catch closer values just outside of it.
+// maxZoom is clipped to [0, ZMax]. Returns ok=false if the index is empty
+func (idx *KeysetIndex) Nearest(lat, lon float64, maxZoom int) (tile Tile, val interface{}, ok bool) {
+	idx.rlockSorted()
+	defer idx.RUnlock()
+	var min float64
+	for _, e := range idx.nearby(lat, lon, 1, maxZoom) {
+		d := distanceToTile(lat, lon, e.Tile)
+		if !ok || d < min {
+			tile, val, min, ok = e.Tile, e.Value, d, true
+		}
+	}
+	return
+}
+
+// KNearest returns up to k stored entries closest to the coordinate sorted by ascending distance, measured to their tiles' centers as in Nearest.
+// It zooms out from maxZoom like Nearest until the neighborhood holds at least k values, then searches rings of tiles at that zoom outward
+// until everything past the last ring is further than the k-th closest value found, so the result is exact.
+// Ties are broken arbitrarily
+func (idx *KeysetIndex) KNearest(lat, lon float64, k int, maxZoom int) []Entry {
+	if k <= 0 {
+		return nil
+	}
+	idx.rlockSorted()
+	defer idx.RUnlock()
+	center := TileFromLatLng(lat, lon, idx.nearZoom(lat, lon, k, maxZoom))
+	var near byDistance
+	seen := make(map[pkey]bool)
+	add := func(key qkey) {
+		tile := key.qk.ToTile()
+		d := distanceToTile(lat, lon, tile)
+		for _, v := range idx.values[key.v] {
+			near.entries = append(near.entries, Entry{Tile: tile, Value: v})
+			near.dists = append(near.dists, d)
+		}
+	}
+	for r := 0; ; r++ {
+		for _, t := range ring(center, r) {
+			qk := packTile(t)
+			if seen[qk] {
+				continue
+			}
+			seen[qk] = true
+			idx.scan(qk, func(key qkey) bool {
+				add(key)
+				return true
+			})
+			// a value at a shallower zoom is under the ring tile holding its center, so it's found by its ancestors
+			for z := 0; z < t.Z; z++ {
+				if p := qk.Parent(z); !seen[p] {
+					seen[p] = true
+					for i := idx.search(p); i < len(idx.keys) && idx.keys[i].qk == p; i++ {
+						add(idx.keys[i])
+					}
+				}
+			}
+		}
+		// only the k closest so far can be in the result
+		sort.Sort(near)
+		if near.Len() > k {
+			near.entries, near.dists = near.entries[:k], near.dists[:k]
+		}
+		if d := beyondRing(lat, lon, center, r); math.IsInf(d, 1) || (near.Len() == k && d >= near.dists[k-1]) {
+			break
+		}
+	}
+	return near.entries
+}
+
+// ring returns the tiles r tiles away from t in x or y, dropping those past the poles and wrapping x.
+// At shallow zooms a wrapped tile may be returned more than once
+func ring(t Tile, r int) (tiles []Tile) {
+	g := grid()
+	add := func(dx, dy int) {
+		if n, ok := g.Neighbor(t, dx, dy); ok {
+			tiles = append(tiles, n)
+		}
+	}
+	if r == 0 {
+		return []Tile{t}
+	}
+	for d := -r; d <= r; d++ {
+		add(d, -r)
+		add(d, r)
+	}
+	for d := -r + 1; d < r; d++ {
+		add(-r, d)
+		add(r, d)
+	}
+	return
+}
+
+// beyondRing returns a lower bound on the distance in meters from the coordinate in the center tile to any tile more than r tiles from it in x or y,
+// the nearest of the rows north and south of the ring and the columns east and west of it. It's +Inf if there are no such tiles
+func beyondRing(lat, lon float64, center Tile, r int) float64 {
+	n := 1 << uint(center.Z)
+	d := math.Inf(1)
+	if center.Y-r > 0 {
+		_, _, north, _ := Tile{X: center.X, Y: center.Y - r, Z: center.Z}.Bounds()
+		d = math.Min(d, DistanceMeters(lat, lon, north, lon))
+	}
+	if center.Y+r < n-1 {
+		south, _, _, _ := Tile{X: center.X, Y: center.Y + r, Z: center.Z}.Bounds()
+		d = math.Min(d, DistanceMeters(lat, lon, south, lon))
+	}
+	if 2*r+1 < n {
+		_, west, _, _ := Tile{X: ((center.X-r)%n + n) % n, Y: center.Y, Z: center.Z}.Bounds()
+		_, _, _, east := Tile{X: (center.X + r) % n, Y: center.Y, Z: center.Z}.Bounds()
+		d = math.Min(d, math.Min(distanceToMeridian(lat, lon, west, MinLat, MaxLat), distanceToMeridian(lat, lon, east, MinLat, MaxLat)))
+	}
+	return d
+}
+
+type byDistance struct {
+	entries []Entry
+	dists   []float64
+}
+
+func (d byDistance) Len() int           { return len(d.entries) }
+func (d byDistance) Less(i, j int) bool { return d.dists[i] < d.dists[j] }
+func (d byDistance) Swap(i, j int) {
+	d.entries[i], d.entries[j] = d.entries[j], d.entries[i]
+	d.dists[i], d.dists[j] = d.dists[j], d.dists[i]
+}
+
+// nearby returns the entries around the coordinate at the deepest zoom <= maxZoom that has at least k,
+// including the neighborhood a zoom further out. If no zoom has k, all entries are returned. maxZoom is clipped to [0, ZMax]
+// Caller must hold a readlock on a sorted index
+func (idx *KeysetIndex) nearby(lat, lon float64, k, maxZoom int) []Entry {
+	z := idx.nearZoom(lat, lon, k, maxZoom)
+	if z > 0 {
+		z--
+	}
+	return idx.around(TileFromLatLng(lat, lon, z))
+}
+
+// nearZoom returns the deepest zoom <= maxZoom where the tile containing the coordinate and its neighbors hold at least k entries, or 0 if none do.
+// maxZoom is clipped to [0, ZMax]
+// Caller must hold a readlock on a sorted index
+func (idx *KeysetIndex) nearZoom(lat, lon float64, k, maxZoom int) int {
+	for z := int(clip(float64(maxZoom), 0, ZMax)); z > 0; z-- {
+		if len(idx.around(TileFromLatLng(lat, lon, z))) >= k {
+			return z
+		}
+	}
+	return 0
+}
+
+// around returns the entries under the tile and its neighbors
+// Caller must hold a readlock on a sorted index
+func (idx *KeysetIndex) around(t Tile) (entries []Entry) {
+	for _, n := range append([]Tile{t}, t.Neighbors()...) {
+		idx.scan(packTile(n), func(k qkey) bool {
+			tile := k.qk.ToTile()
+			for _, v := range idx.values[k.v] {
+				entries = append(entries, Entry{Tile: tile, Value: v})
+			}
+			return true
+		})
+	}
+	return
+}
+
+// distanceToTile returns the distance in meters from the coordinate to the center of the tile
+func distanceToTile(lat, lon float64, t Tile) float64 {
+	clat, clon := t.Center()
+	return DistanceMeters(lat, lon, clat, clon)
+}
@@ -0,0 +1,114 @@
+package tiles
+
+import (
+	"math/rand"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestKeysetIndexNearest(t *testing.T) {
+	idx := &KeysetIndex{}
+	if _, _, ok := idx.Nearest(40.7484, -73.9857, 18); ok {
+		t.Error("Nearest on an empty index returned ok")
+	}
+	esb := FromCoordinate(40.7484, -73.9857, 18)
+	sol := FromCoordinate(40.6892, -74.0445, 18)
+	bbn := FromCoordinate(51.5007, -0.1246, 18)
+	suva := FromCoordinate(-18.1416, 178.4419, 18)
+	idx.Add(esb, "EmpireStateBuilding")
+	idx.Add(sol, "StatueOfLiberty")
+	idx.Add(bbn, "BigBen")
+	idx.Add(suva, "Suva")
+	tests := []struct {
+		lat, lon float64
+		tile     Tile
+		val      interface{}
+	}{
+		{40.7484, -73.9857, esb, "EmpireStateBuilding"},
+		{40.7580, -73.9855, esb, "EmpireStateBuilding"},
+		{40.7000, -74.0300, sol, "StatueOfLiberty"},
+		{48.8584, 2.2945, bbn, "BigBen"},
+		{-17.7134, -178.0650, suva, "Suva"},
+	}
+	errf := "Nearest(%v, %v, 18) -> %+v, %v, %v"
+	for _, test := range tests {
+		tile, val, ok := idx.Nearest(test.lat, test.lon, 18)
+		if !ok || tile != test.tile || val != test.val {
+			t.Errorf(errf, test.lat, test.lon, tile, val, ok)
+		}
+	}
+	for _, zoom := range []int{-1, 30} {
+		if tile, val, ok := idx.Nearest(40.7484, -73.9857, zoom); !ok || tile != esb || val != "EmpireStateBuilding" {
+			t.Errorf("Nearest(40.7484, -73.9857, %d) -> %+v, %v, %v", zoom, tile, val, ok)
+		}
+	}
+}
+
+func TestKeysetIndexKNearest(t *testing.T) {
+	idx := &KeysetIndex{}
+	if entries := idx.KNearest(40.7484, -73.9857, 3, 18); len(entries) != 0 {
+		t.Error("KNearest on an empty index: ", entries)
+	}
+	esb := FromCoordinate(40.7484, -73.9857, 18)
+	sol := FromCoordinate(40.6892, -74.0445, 18)
+	bbn := FromCoordinate(51.5007, -0.1246, 18)
+	lib := FromCoordinate(40.7532, -73.9822, 18)
+	idx.Add(esb, "EmpireStateBuilding")
+	idx.Add(sol, "StatueOfLiberty")
+	idx.Add(bbn, "BigBen")
+	idx.Add(lib, "PublicLibrary")
+	tests := []struct {
+		k    int
+		vals []interface{}
+	}{
+		{0, nil},
+		{1, []interface{}{"EmpireStateBuilding"}},
+		{2, []interface{}{"EmpireStateBuilding", "PublicLibrary"}},
+		{3, []interface{}{"EmpireStateBuilding", "PublicLibrary", "StatueOfLiberty"}},
+		{10, []interface{}{"EmpireStateBuilding", "PublicLibrary", "StatueOfLiberty", "BigBen"}},
+	}
+	errf := "KNearest(40.7484, -73.9857, %d, 18) -> %v"
+	for _, test := range tests {
+		var vals []interface{}
+		for _, e := range idx.KNearest(40.7484, -73.9857, test.k, 18) {
+			vals = append(vals, e.Value)
+		}
+		if !reflect.DeepEqual(vals, test.vals) {
+			t.Errorf(errf, test.k, vals)
+		}
+	}
+	// brute force over every entry, with values at a few shallow zooms that aren't under any deep tile
+	rng := rand.New(rand.NewSource(1))
+	idx = &KeysetIndex{}
+	var all []Entry
+	for i := 0; i < 500; i++ {
+		zoom := 10 + rng.Intn(9)
+		if i%50 == 0 {
+			zoom = 2 + rng.Intn(5)
+		}
+		e := Entry{Tile: TileFromLatLng(rng.Float64()*40+20, rng.Float64()*60-100, zoom), Value: i}
+		idx.Add(e.Tile, e.Value)
+		all = append(all, e)
+	}
+	for i := 0; i < 100; i++ {
+		lat, lon := rng.Float64()*50+15, rng.Float64()*80-110
+		k, maxZoom := 1+rng.Intn(20), []int{8, 14, 18, 30}[rng.Intn(4)]
+		dists := make([]float64, len(all))
+		for j, e := range all {
+			dists[j] = distanceToTile(lat, lon, e.Tile)
+		}
+		sort.Float64s(dists)
+		entries := idx.KNearest(lat, lon, k, maxZoom)
+		if len(entries) != k {
+			t.Errorf("KNearest(%v, %v, %d, %d) -> %d entries", lat, lon, k, maxZoom, len(entries))
+			continue
+		}
+		for j, e := range entries {
+			// ties break arbitrarily, so only the distances are compared
+			if d := distanceToTile(lat, lon, e.Tile); d != dists[j] {
+				t.Errorf("KNearest(%v, %v, %d, %d)[%d] is %vm away, not %vm", lat, lon, k, maxZoom, j, d, dists[j])
+			}
+		}
+	}
+}
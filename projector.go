@@ -0,0 +1,37 @@
+package tiles
+
+import "math"
+
+// Projector transforms WGS84 coordinates into the x, y of another coordinate reference system
+type Projector interface {
+	Forward(lat, lon float64) (x, y float64)
+}
+
+// WebMercatorProjector projects WGS84 coordinates to web mercator (EPSG:3857) meters, the projection tiles are cut in.
+// Latitude is clipped to Min/MaxLat, so the poles land on the edges of the map
+type WebMercatorProjector struct{}
+
+// Forward returns the web mercator x, y in meters of the coordinate
+func (WebMercatorProjector) Forward(lat, lon float64) (x, y float64) {
+	lat = clip(lat, MinLat, MaxLat)
+	x = lon * math.Pi / 180 * EarthRadiusM
+	y = math.Log(math.Tan(math.Pi/4+lat*math.Pi/360)) * EarthRadiusM
+	return
+}
+
+// BoundsIn returns the bbox of the tile's four corners projected by p.
+// Only the corners are projected, so in projections where the tile's edges curve outward the bbox can be slightly smaller than the projected tile
+func (t Tile) BoundsIn(p Projector) (minX, minY, maxX, maxY float64) {
+	minLat, minLon, maxLat, maxLon := t.Bounds()
+	corners := [4][2]float64{{maxLat, minLon}, {maxLat, maxLon}, {minLat, maxLon}, {minLat, minLon}}
+	for i, c := range corners {
+		x, y := p.Forward(c[0], c[1])
+		if i == 0 {
+			minX, minY, maxX, maxY = x, y, x, y
+			continue
+		}
+		minX, minY = math.Min(minX, x), math.Min(minY, y)
+		maxX, maxY = math.Max(maxX, x), math.Max(maxY, y)
+	}
+	return
+}
@@ -0,0 +1,30 @@
+package tiles
+
+import (
+	"math"
+	"testing"
+)
+
+// plateCarree projects to degrees as is, x is longitude and y is latitude
+type plateCarree struct{}
+
+func (plateCarree) Forward(lat, lon float64) (x, y float64) {
+	return lon, lat
+}
+
+func TestTileBoundsIn(t *testing.T) {
+	tiles := []Tile{{X: 0, Y: 0, Z: 0}, {X: 1, Y: 0, Z: 1}, {X: 0, Y: 1, Z: 1}, {X: 26, Y: 48, Z: 7}, {X: 77197, Y: 98526, Z: 18}}
+	errf := "%+v.BoundsIn(%T) -> %v, %v, %v, %v"
+	for _, tile := range tiles {
+		minX, minY, maxX, maxY := tile.BoundsIn(WebMercatorProjector{})
+		eMinX, eMinY, eMaxX, eMaxY := tile.BoundsMeters()
+		if math.Abs(minX-eMinX) > 1e-3 || math.Abs(minY-eMinY) > 1e-3 || math.Abs(maxX-eMaxX) > 1e-3 || math.Abs(maxY-eMaxY) > 1e-3 {
+			t.Errorf(errf, tile, WebMercatorProjector{}, minX, minY, maxX, maxY)
+		}
+		minX, minY, maxX, maxY = tile.BoundsIn(plateCarree{})
+		minLat, minLon, maxLat, maxLon := tile.Bounds()
+		if minX != minLon || minY != minLat || maxX != maxLon || maxY != maxLat {
+			t.Errorf(errf, tile, plateCarree{}, minX, minY, maxX, maxY)
+		}
+	}
+}
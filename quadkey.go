@@ -1,9 +1,38 @@
 package tiles
 
+import (
+	"errors"
+	"strconv"
+	"strings"
+)
+
 // Quadkey represents a Bing Maps quadkey
 // It can also be used as a quadtree data structure
 type Quadkey string
 
+// ValidQuadkey returns true if every character of qk is 0-3 and it's no deeper than ZMax
+func ValidQuadkey(qk string) bool {
+	if len(qk) > ZMax {
+		return false
+	}
+	for i := 0; i < len(qk); i++ {
+		if qk[i] < '0' || qk[i] > '3' {
+			return false
+		}
+	}
+	return true
+}
+
+// NormalizeQuadkey returns qk with surrounding whitespace trimmed, or an error if what's left isn't a ValidQuadkey.
+// Nothing else is stripped, so prefixes such as region codes still need removing first
+func NormalizeQuadkey(qk string) (Quadkey, error) {
+	trimmed := strings.TrimSpace(qk)
+	if !ValidQuadkey(trimmed) {
+		return "", errors.New("Invalid Quadkey " + strconv.Quote(qk))
+	}
+	return Quadkey(trimmed), nil
+}
+
 // HasParent returns a true if o is a parent of q.
 // If q == o, it return false
 func (q Quadkey) HasParent(o Quadkey) bool {
@@ -25,6 +54,15 @@ func (q Quadkey) Level() int {
 	return len(q)
 }
 
+// Prefixes returns the quadkey's ancestors from the root down followed by the quadkey itself, so the root quadkey returns [""]
+func (q Quadkey) Prefixes() []Quadkey {
+	prefixes := make([]Quadkey, len(q)+1)
+	for z := range prefixes {
+		prefixes[z] = q[:z]
+	}
+	return prefixes
+}
+
 // Children returns a slice of the the Quadkeys in the next level of this tree
 func (q Quadkey) Children() []Quadkey {
 	return []Quadkey{
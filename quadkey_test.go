@@ -4,6 +4,80 @@ import (
 	"testing"
 )
 
+func TestValidQuadkey(t *testing.T) {
+	tests := []struct {
+		q  string
+		ok bool
+	}{
+		{"", true},
+		{"0123", true},
+		{"03201011013202332123333", true},
+		{"032010110132023321233330", false},
+		{"0124", false},
+		{"012 ", false},
+		{"a", false},
+		{"/", false},
+	}
+	errf := "ValidQuadkey(%q) -> %v"
+	for _, test := range tests {
+		if ok := ValidQuadkey(test.q); ok != test.ok {
+			t.Errorf(errf, test.q, ok)
+		}
+		if _, err := FromQuadkeyString(test.q); (err == nil) != test.ok {
+			t.Errorf("FromQuadkeyString(%q) -> %v", test.q, err)
+		}
+	}
+}
+
+func TestNormalizeQuadkey(t *testing.T) {
+	tests := []struct {
+		q  string
+		qk Quadkey
+		ok bool
+	}{
+		{"0123", "0123", true},
+		{"0123 ", "0123", true},
+		{" \t0123\r\n", "0123", true},
+		{"", "", true},
+		{"  ", "", true},
+		{"01 23", "", false},
+		{"A0123", "", false},
+		{"0124", "", false},
+		{" 032010110132023321233330 ", "", false},
+	}
+	errf := "NormalizeQuadkey(%q) -> %q, %v"
+	for _, test := range tests {
+		qk, err := NormalizeQuadkey(test.q)
+		if qk != test.qk || (err == nil) != test.ok {
+			t.Errorf(errf, test.q, qk, err)
+		}
+	}
+}
+
+func TestQuadkeyPrefixes(t *testing.T) {
+	tests := []struct {
+		q        Quadkey
+		prefixes []Quadkey
+	}{
+		{"", []Quadkey{""}},
+		{"0", []Quadkey{"", "0"}},
+		{"0213", []Quadkey{"", "0", "02", "021", "0213"}},
+	}
+	errf := "Quadkey(%q).Prefixes() -> %q"
+	for _, test := range tests {
+		prefixes := test.q.Prefixes()
+		if len(prefixes) != len(test.prefixes) {
+			t.Errorf(errf, test.q, prefixes)
+			continue
+		}
+		for i, p := range prefixes {
+			if p != test.prefixes[i] {
+				t.Errorf(errf, test.q, prefixes)
+			}
+		}
+	}
+}
+
 func TestQuadkeyHasParent(t *testing.T) {
 	tests := []struct {
 		q Quadkey
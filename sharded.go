@@ -0,0 +1,81 @@
+package tiles
+
+import (
+	"context"
+	"hash/fnv"
+)
+
+// ShardLevel is the quadkey level whose prefix routes a key to a ShardedIndex shard.
+// Tiles at or deeper than this level are served by a single shard, shallower tiles fan out to all of them.
+const ShardLevel = 8
+
+// ShardedIndex is a TileIndex that spreads keys across several KeysetIndexes so writers to different regions don't contend.
+// Values for tiles shallower than ShardLevel are the concatenation of each shard's values, so they are not in quadkey order.
+// ShardedIndex is thread safe
+type ShardedIndex struct {
+	shards []*KeysetIndex
+}
+
+// NewShardedIndex returns an empty ShardedIndex with the given number of shards, at least one
+func NewShardedIndex(shards int) *ShardedIndex {
+	if shards < 1 {
+		shards = 1
+	}
+	idx := &ShardedIndex{shards: make([]*KeysetIndex, shards)}
+	for i := range idx.shards {
+		idx.shards[i] = &KeysetIndex{}
+	}
+	return idx
+}
+
+// TileRange returns a channel of all tiles in the index in the zoom range from each shard in turn
+// Tiles shallower than ShardLevel can be emitted by more than one shard
+func (idx *ShardedIndex) TileRange(zmin, zmax int) <-chan Tile {
+	return idx.TileRangeContext(context.Background(), zmin, zmax)
+}
+
+// TileRangeContext is TileRange that stops sending and closes the channel when ctx is done.
+func (idx *ShardedIndex) TileRangeContext(ctx context.Context, zmin, zmax int) <-chan Tile {
+	tiles := make(chan Tile, 1<<10)
+	go func() {
+		defer close(tiles)
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+		for _, s := range idx.shards {
+			for t := range s.TileRangeContext(ctx, zmin, zmax) {
+				select {
+				case tiles <- t:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+	return tiles
+}
+
+// Values returns a list of values aggregated under the requested tile
+func (idx *ShardedIndex) Values(t Tile) (vals []interface{}) {
+	if t.Z >= ShardLevel {
+		return idx.shard(t.Quadkey()).Values(t)
+	}
+	for _, s := range idx.shards {
+		vals = append(vals, s.Values(t)...)
+	}
+	return
+}
+
+// Add adds values to the tile in the shard that owns it
+func (idx *ShardedIndex) Add(t Tile, val ...interface{}) {
+	idx.shard(t.Quadkey()).Add(t, val...)
+}
+
+// shard returns the shard owning the quadkey by hashing its ShardLevel prefix
+func (idx *ShardedIndex) shard(qk Quadkey) *KeysetIndex {
+	if qk.Level() > ShardLevel {
+		qk = qk.Parent(ShardLevel)
+	}
+	h := fnv.New32a()
+	h.Write([]byte(qk))
+	return idx.shards[h.Sum32()%uint32(len(idx.shards))]
+}
@@ -0,0 +1,38 @@
+package tiles
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestShardedIndex(t *testing.T) {
+	for _, n := range []int{0, 1, 4, 16} {
+		idx := NewShardedIndex(n)
+		testIndex(t, TileIndex(idx))
+	}
+}
+
+func TestShardedIndexConcurrent(t *testing.T) {
+	idx := NewShardedIndex(8)
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < 1000; i++ {
+				idx.Add(Tile{X: g*1000 + i, Y: i, Z: 18}, i)
+			}
+		}(g)
+	}
+	wg.Wait()
+	if n := len(idx.Values(Tile{})); n != 8000 {
+		t.Error("ShardedIndex expected 8000 values, got ", n)
+	}
+	seen := make(map[Tile]struct{})
+	for tile := range idx.TileRange(18, 18) {
+		seen[tile] = struct{}{}
+	}
+	if len(seen) != 8000 {
+		t.Error("ShardedIndex.TileRange expected 8000 tiles, got ", len(seen))
+	}
+}
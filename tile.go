@@ -3,20 +3,22 @@
 // There is also a TileIndex which can be used to store data in a single place and aggregate when needed
 package tiles
 
-import "errors"
+import (
+	"errors"
+	"math"
+	"strconv"
+	"strings"
+)
 
 // Tile is a simple struct for holding the XYZ coordinates for use in mapping
+// The exported X, Y and Z fields are its API, it's a comparable value type that can be used as a map key
 type Tile struct {
 	X, Y, Z int
 }
 
 // ToPixel return the NW pixel of this tile
 func (t Tile) ToPixel() Pixel {
-	return Pixel{
-		X: t.X * TileSize,
-		Y: t.Y * TileSize,
-		Z: t.Z,
-	}
+	return grid().TileToPixel(t)
 }
 
 // ToPixelWithOffset returns a pixel at the origin with an offset added. Useful for getting the center pixel of a tile or another non-origin pixel.
@@ -61,8 +63,344 @@ func (t Tile) Quadkey() Quadkey {
 	return Quadkey(qk[:z]) // current bottleneck
 }
 
-// FromQuadkeyString returns a tile that represents the given quadkey string. Returns an error if quadkey string is invalid.
+// Bounds returns the WGS84 extent of the tile.
+// Latitudes are clipped to Min/MaxLat, so the zoom 0 tile returns the whole map
+func (t Tile) Bounds() (minLat, minLon, maxLat, maxLon float64) {
+	n := float64(uint(1) << uint(t.Z))
+	minLon = float64(t.X)/n*360 - 180
+	maxLon = float64(t.X+1)/n*360 - 180
+	maxLat = clip(tileLat(float64(t.Y), n), MinLat, MaxLat)
+	minLat = clip(tileLat(float64(t.Y+1), n), MinLat, MaxLat)
+	return
+}
+
+// IntersectBBox returns the part of the tile's Bounds inside the bbox and true, or false if they don't overlap.
+// Tiles and bboxes that only share an edge don't overlap. The bbox can't cross the antimeridian
+func (t Tile) IntersectBBox(minLat, minLon, maxLat, maxLon float64) (iMinLat, iMinLon, iMaxLat, iMaxLon float64, ok bool) {
+	tMinLat, tMinLon, tMaxLat, tMaxLon := t.Bounds()
+	iMinLat, iMaxLat = math.Max(minLat, tMinLat), math.Min(maxLat, tMaxLat)
+	iMinLon, iMaxLon = math.Max(minLon, tMinLon), math.Min(maxLon, tMaxLon)
+	if iMinLat >= iMaxLat || iMinLon >= iMaxLon {
+		return 0, 0, 0, 0, false
+	}
+	return iMinLat, iMinLon, iMaxLat, iMaxLon, true
+}
+
+// Buffered returns the tile's Bounds grown by meters on every side, for covering the tile and its surroundings with TilesForBBox at the same zoom.
+// The longitude buffer is taken at the tile's edge furthest from the equator, where a degree is shortest, so every point within meters of the tile is inside.
+// Latitudes are clipped to Min/MaxLat. If the buffer reaches a pole or wraps the globe the longitudes are the whole range,
+// otherwise they're wrapped and minLon > maxLon when the bbox crosses the antimeridian. A negative buffer is treated as 0
+func (t Tile) Buffered(meters float64) (minLat, minLon, maxLat, maxLon float64) {
+	minLat, minLon, maxLat, maxLon = t.Bounds()
+	dLat, dLon, ok := degreesForMeters(math.Max(math.Abs(minLat), math.Abs(maxLat)), math.Max(meters, 0))
+	if !ok || maxLon-minLon+2*dLon >= 360 {
+		minLon, maxLon = MinLon, MaxLon
+	} else {
+		minLon, maxLon = minLon-dLon, maxLon+dLon
+		if minLon < MinLon {
+			minLon += 360
+		}
+		if maxLon > MaxLon {
+			maxLon -= 360
+		}
+	}
+	minLat, maxLat = clip(minLat-dLat, MinLat, MaxLat), clip(maxLat+dLat, MinLat, MaxLat)
+	return
+}
+
+// BoundsMeters returns the extent of the tile in web mercator (EPSG:3857) meters.
+// The projection's origin is at the center of the map with y growing north, unlike tile rows which grow south
+func (t Tile) BoundsMeters() (minX, minY, maxX, maxY float64) {
+	size := 2 * mercatorExtent / float64(uint(1)<<uint(t.Z))
+	minX = float64(t.X)*size - mercatorExtent
+	maxX = minX + size
+	maxY = mercatorExtent - float64(t.Y)*size
+	minY = maxY - size
+	return
+}
+
+// TileFromMeters returns the tile at zoom containing the web mercator (EPSG:3857) point.
+// Points outside of the projection's extent are clipped to the tiles at its edges
+func TileFromMeters(x, y float64, zoom int) Tile {
+	n := float64(uint(1) << uint(zoom))
+	size := 2 * mercatorExtent / n
+	return Tile{
+		X: int(clip(math.Floor((x+mercatorExtent)/size), 0, n-1)),
+		Y: int(clip(math.Floor((mercatorExtent-y)/size), 0, n-1)),
+		Z: zoom,
+	}
+}
+
+// Corners returns the corners of the tile's Bounds as {lat, lon} points, clockwise from the north west corner: NW, NE, SE, SW.
+// The ring isn't closed, so append the first corner to close it for GeoJSON or WKT
+func (t Tile) Corners() [4][2]float64 {
+	minLat, minLon, maxLat, maxLon := t.Bounds()
+	return [4][2]float64{{maxLat, minLon}, {maxLat, maxLon}, {minLat, maxLon}, {minLat, minLon}}
+}
+
+// WKT returns the tile's Bounds as a WKT POLYGON with "lon lat" points.
+// The ring runs counter-clockwise from the south west corner and repeats it at the end to close the ring
+func (t Tile) WKT() string {
+	minLat, minLon, maxLat, maxLon := t.Bounds()
+	ring := [][2]float64{{minLon, minLat}, {maxLon, minLat}, {maxLon, maxLat}, {minLon, maxLat}, {minLon, minLat}}
+	points := make([]string, len(ring))
+	for i, p := range ring {
+		points[i] = strconv.FormatFloat(p[0], 'f', -1, 64) + " " + strconv.FormatFloat(p[1], 'f', -1, 64)
+	}
+	return "POLYGON((" + strings.Join(points, ", ") + "))"
+}
+
+// SubTileAtPixel returns the descendant of the tile deltaZoom levels down that contains the pixel px, py within the TileSize pixel tile.
+// Pixels on or past the tile's edges are clamped into it rather than spilling into a neighbor
+func (t Tile) SubTileAtPixel(px, py, deltaZoom int) Tile {
+	return grid().SubTileAtPixel(t, px, py, deltaZoom)
+}
+
+// Center returns the midpoint of the tile's Bounds
+func (t Tile) Center() (lat, lon float64) {
+	minLat, minLon, maxLat, maxLon := t.Bounds()
+	return (minLat + maxLat) / 2, (minLon + maxLon) / 2
+}
+
+// Contains returns true if the coordinate is within the tile's Bounds.
+// The north and west edges are inclusive and the south and east edges exclusive, so a point on a shared edge is only in one tile
+func (t Tile) Contains(lat, lon float64) bool {
+	minLat, minLon, maxLat, maxLon := t.Bounds()
+	return lat > minLat && lat <= maxLat && lon >= minLon && lon < maxLon
+}
+
+// Equal returns true if the tiles have the same x, y and zoom, the same as ==
+func (t Tile) Equal(o Tile) bool {
+	return t == o
+}
+
+// Less returns true if the tile's quadkey sorts before o's, the order an index keeps its keys in.
+// It's a Z-order traversal where a tile sorts before its descendants and after its ancestors. Panics if either tile is invalid
+func (t Tile) Less(o Tile) bool {
+	return packTile(t) < packTile(o)
+}
+
+// ContainsTile returns true if other is this tile or one of its descendants, i.e. t's quadkey is a prefix of other's.
+// It's named apart from Contains, which tests a coordinate
+func (t Tile) ContainsTile(other Tile) bool {
+	return strings.HasPrefix(string(other.Quadkey()), string(t.Quadkey()))
+}
+
+// Overlaps returns true if either tile contains the other. Tiles that don't overlap share no area
+func (t Tile) Overlaps(other Tile) bool {
+	return t.ContainsTile(other) || other.ContainsTile(t)
+}
+
+// ResolutionMeters returns the ground resolution in meters per pixel at the tile's center for TileSize pixel tiles.
+// It's the width of the tile in meters at that latitude divided by TileSize
+func (t Tile) ResolutionMeters() float64 {
+	return grid().ResolutionMeters(t)
+}
+
+// DistanceTo returns the great-circle distance in meters between the centers of the two tiles
+func (t Tile) DistanceTo(other Tile) float64 {
+	lat1, lon1 := t.Center()
+	lat2, lon2 := other.Center()
+	return DistanceMeters(lat1, lon1, lat2, lon2)
+}
+
+// BearingTo returns the initial great-circle bearing in degrees clockwise from north from the center of t to the center of other, in the range [0, 360)
+func (t Tile) BearingTo(other Tile) float64 {
+	lat1, lon1 := t.Center()
+	lat2, lon2 := other.Center()
+	return BearingDegrees(lat1, lon1, lat2, lon2)
+}
+
+// latitude of the northern edge of tile row y in a map n tiles wide
+func tileLat(y, n float64) float64 {
+	return math.Atan(math.Sinh(math.Pi*(1-2*y/n))) * 180 / math.Pi
+}
+
+// Parent returns the tile one level up that contains this tile. The zoom 0 tile is its own parent
+func (t Tile) Parent() Tile {
+	if t.Z <= 0 {
+		return t
+	}
+	return t.Quadkey().Parent(t.Z - 1).ToTile()
+}
+
+// AtZoom returns the tile at zoom covering the same place. A shallower zoom returns the ancestor, as repeated Parent calls would,
+// and a deeper zoom returns the north-west descendant, the one whose quadkey appends 0s to the tile's. zoom is clipped to [0, ZMax]
+func (t Tile) AtZoom(zoom int) Tile {
+	zoom = int(clip(float64(zoom), 0, ZMax))
+	if zoom <= t.Z {
+		d := uint(t.Z - zoom)
+		return Tile{X: t.X >> d, Y: t.Y >> d, Z: zoom}
+	}
+	d := uint(zoom - t.Z)
+	return Tile{X: t.X << d, Y: t.Y << d, Z: zoom}
+}
+
+// ChildrenAtZoom returns the 4^(zoom-t.Z) descendants of the tile at zoom in row-major order, north to south then west to east.
+// It's empty if zoom isn't deeper than the tile or is past ZMax
+func (t Tile) ChildrenAtZoom(zoom int) (children []Tile) {
+	if zoom <= t.Z || zoom > ZMax {
+		return
+	}
+	d := uint(zoom - t.Z)
+	children = make([]Tile, 0, 1<<(2*d))
+	for y := t.Y << d; y < (t.Y+1)<<d; y++ {
+		for x := t.X << d; x < (t.X+1)<<d; x++ {
+			children = append(children, Tile{X: x, Y: y, Z: zoom})
+		}
+	}
+	return
+}
+
+// Ancestors returns the tile's parent, grandparent and so on up to and including the ancestor at upToZoom, deepest first.
+// The tile itself isn't included, so it's empty if upToZoom isn't above the tile's zoom. upToZoom is clipped to 0
+func (t Tile) Ancestors(upToZoom int) (ancestors []Tile) {
+	if upToZoom < 0 {
+		upToZoom = 0
+	}
+	qk := t.Quadkey()
+	for z := t.Z - 1; z >= upToZoom; z-- {
+		ancestors = append(ancestors, qk.Parent(z).ToTile())
+	}
+	return
+}
+
+// Children returns the four tiles in the next zoom level that this tile contains, in quadkey order
+func (t Tile) Children() (children [4]Tile) {
+	for i, qk := range t.Quadkey().Children() {
+		children[i] = qk.ToTile()
+	}
+	return
+}
+
+// Quadrant returns the tile's position within its parent, the last digit of its quadkey and its index in the parent's Children.
+// The zoom 0 tile has no parent and returns 0
+func (t Tile) Quadrant() int {
+	if t.Z <= 0 {
+		return 0
+	}
+	return t.X&1 | (t.Y&1)<<1
+}
+
+// Siblings returns the parent's other three children in quadkey order.
+// The zoom 0 tile has no siblings and returns the zero value
+func (t Tile) Siblings() (siblings [3]Tile) {
+	if t.Z <= 0 {
+		return
+	}
+	i := 0
+	for _, c := range t.Parent().Children() {
+		if c != t {
+			siblings[i] = c
+			i++
+		}
+	}
+	return
+}
+
+// CommonAncestor returns the deepest tile that contains both tiles, the longest prefix their quadkeys share.
+// If either tile contains the other it's returned, and tiles that share no prefix return the zoom 0 tile
+func CommonAncestor(a, b Tile) Tile {
+	qa, qb := a.Quadkey(), b.Quadkey()
+	z := 0
+	for z < len(qa) && z < len(qb) && qa[z] == qb[z] {
+		z++
+	}
+	return qa.Parent(z).ToTile()
+}
+
+// Neighbor returns the tile offset by dx, dy at the same zoom.
+// x wraps around the antimeridian, but there's nothing past the poles so ok is false if y is out of range
+func (t Tile) Neighbor(dx, dy int) (tile Tile, ok bool) {
+	return grid().Neighbor(t, dx, dy)
+}
+
+// Neighbors returns the adjacent tiles at the same zoom in N, NE, E, SE, S, SW, W, NW order.
+// Tiles in the top and bottom rows have no vertical neighbors.
+// At shallow zooms where wrapping makes neighbors coincide, each tile is returned once and t itself is omitted
+func (t Tile) Neighbors() (tiles []Tile) {
+	return grid().Neighbors(t)
+}
+
+// String returns the tile in the slippy map "z/x/y" form
+func (t Tile) String() string {
+	return strconv.Itoa(t.Z) + "/" + strconv.Itoa(t.X) + "/" + strconv.Itoa(t.Y)
+}
+
+// TMS returns the tile's TMS coordinates, which count y up from the south rather than down from the north
+func (t Tile) TMS() (x, y, z int) {
+	return t.X, 1<<uint(t.Z) - 1 - t.Y, t.Z
+}
+
+// TileFromTMS returns the tile at the TMS coordinates, the inverse of Tile.TMS
+func TileFromTMS(x, y, z int) Tile {
+	return Tile{X: x, Y: 1<<uint(z) - 1 - y, Z: z}
+}
+
+// ParseTile returns the tile represented by a "z/x/y" string. Returns an error if the string is malformed,
+// the zoom is outside of 0-ZMax or x/y are outside of the tile grid at that zoom.
+func ParseTile(s string) (tile Tile, err error) {
+	parts := strings.Split(s, "/")
+	if len(parts) != 3 {
+		return Tile{}, errors.New("Invalid Tile " + s)
+	}
+	var zxy [3]int
+	for i, p := range parts {
+		if zxy[i], err = strconv.Atoi(p); err != nil {
+			return Tile{}, errors.New("Invalid Tile " + s)
+		}
+	}
+	tile = Tile{X: zxy[1], Y: zxy[2], Z: zxy[0]}
+	n := 1 << uint(tile.Z)
+	if tile.Z < 0 || tile.Z > ZMax || tile.X < 0 || tile.X >= n || tile.Y < 0 || tile.Y >= n {
+		return Tile{}, errors.New("Invalid Tile " + s)
+	}
+	return
+}
+
+// ToUint64 packs the tile into a uint64 with the zoom in the top 6 bits followed by 29 bits each of x and y.
+// The packing is exact for tiles up to zoom 29, which includes every tile to ZMax
+func (t Tile) ToUint64() uint64 {
+	return uint64(t.Z)<<58 | uint64(t.X)<<29 | uint64(t.Y)
+}
+
+// TileFromUint64 returns the tile packed by Tile.ToUint64
+func TileFromUint64(u uint64) Tile {
+	const mask = 1<<29 - 1
+	return Tile{
+		X: int(u >> 29 & mask),
+		Y: int(u & mask),
+		Z: int(u >> 58),
+	}
+}
+
+// Morton returns the tile's Morton (Z-order) code, x and y interleaved with x in the even bits and y in the odd bits.
+// Read two bits at a time from the top it's the tile's quadkey as a base 4 number, but it doesn't encode the zoom,
+// so tiles at different zooms can share a code and TileFromMorton needs the zoom to decode it
+func (t Tile) Morton() (code uint64) {
+	for i := uint(0); i < uint(t.Z); i++ {
+		code |= uint64(t.X>>i&1)<<(2*i) | uint64(t.Y>>i&1)<<(2*i+1)
+	}
+	return
+}
+
+// TileFromMorton returns the tile at zoom with the Morton code, the inverse of Tile.Morton. Bits above 2*zoom are ignored
+func TileFromMorton(code uint64, zoom int) (t Tile) {
+	t.Z = zoom
+	for i := uint(0); i < uint(zoom); i++ {
+		t.X |= int(code>>(2*i)&1) << i
+		t.Y |= int(code>>(2*i+1)&1) << i
+	}
+	return
+}
+
+// FromQuadkeyString returns a tile that represents the given quadkey string.
+// Returns an error if quadkey string is invalid or deeper than ZMax.
 func FromQuadkeyString(qk string) (tile Tile, err error) {
+	if len(qk) > ZMax {
+		err = errors.New("Invalid Quadkey " + qk)
+		return
+	}
 	tile.Z = len(qk)
 	for i := tile.Z; i > 0; i-- {
 		m := 1 << uint(i-1)
@@ -94,3 +432,19 @@ func FromCoordinate(lat, lon float64, zoom int) Tile {
 	t, _ := p.ToTile()
 	return t
 }
+
+// TileFromLatLng returns the tile containing the WGS84 coordinates at the zoom.
+// Unlike FromCoordinate, which clips longitude, it wraps longitude into [-180, 180) so points past the antimeridian land on the other side.
+// Latitude is clipped to Min/MaxLat. The tile is found from the point's position on the map rather than its rounded pixel,
+// so the tile always Contains the point, which FromCoordinate misses for points within half a pixel of a tile's edge
+func TileFromLatLng(lat, lon float64, zoom int) Tile {
+	x, y := tileCell(lat, wrapLon(lon), zoom)
+	return Tile{X: x, Y: y, Z: zoom}
+}
+
+// TileFromLatLngMax returns the tile containing the WGS84 coordinates at ZMax like TileFromLatLng, the finest tile a quadkey can hold.
+// Adding values at it and letting Values aggregate them up answers queries at any zoom, but each key costs ZMax digits as a Quadkey
+// and deep keys spread values thinly, so truncate with Ancestors or NewKeysetIndexMaxZoom when that precision isn't needed
+func TileFromLatLngMax(lat, lon float64) Tile {
+	return TileFromLatLng(lat, lon, ZMax)
+}
@@ -2,6 +2,10 @@ package tiles_test
 
 import (
 	"fmt"
+	"math"
+	"math/rand"
+	"reflect"
+	"strconv"
 	"testing"
 
 	"github.com/buckhx/tiles"
@@ -56,6 +60,788 @@ func TestTileFromQuadkey(t *testing.T) {
 	}
 }
 
+func TestTileParent(t *testing.T) {
+	tileTests := []struct {
+		tile   tiles.Tile
+		parent tiles.Tile
+	}{
+		{tiles.Tile{X: 0, Y: 0, Z: 0}, tiles.Tile{X: 0, Y: 0, Z: 0}},
+		{tiles.Tile{X: 1, Y: 1, Z: 1}, tiles.Tile{X: 0, Y: 0, Z: 0}},
+		{tiles.Tile{X: 26, Y: 48, Z: 7}, tiles.Tile{X: 13, Y: 24, Z: 6}},
+		{tiles.Tile{X: 27, Y: 49, Z: 7}, tiles.Tile{X: 13, Y: 24, Z: 6}},
+	}
+	errf := "Tile%+v: %+v -> %+v"
+	for _, test := range tileTests {
+		parent := test.tile.Parent()
+		if parent != test.parent {
+			t.Errorf(errf, test.tile, test.parent, parent)
+		}
+	}
+}
+
+func TestTileChildren(t *testing.T) {
+	tileTests := []struct {
+		tile     tiles.Tile
+		children [4]tiles.Tile
+	}{
+		{tiles.Tile{X: 0, Y: 0, Z: 0}, [4]tiles.Tile{{X: 0, Y: 0, Z: 1}, {X: 1, Y: 0, Z: 1}, {X: 0, Y: 1, Z: 1}, {X: 1, Y: 1, Z: 1}}},
+		{tiles.Tile{X: 13, Y: 24, Z: 6}, [4]tiles.Tile{{X: 26, Y: 48, Z: 7}, {X: 27, Y: 48, Z: 7}, {X: 26, Y: 49, Z: 7}, {X: 27, Y: 49, Z: 7}}},
+	}
+	errf := "Tile%+v: %+v -> %+v"
+	for _, test := range tileTests {
+		children := test.tile.Children()
+		if children != test.children {
+			t.Errorf(errf, test.tile, test.children, children)
+		}
+		for _, c := range children {
+			if c.Parent() != test.tile {
+				t.Errorf("Tile%+v.Parent() -> %+v", c, c.Parent())
+			}
+		}
+	}
+}
+
+func TestTileNeighbor(t *testing.T) {
+	tileTests := []struct {
+		tile     tiles.Tile
+		dx, dy   int
+		neighbor tiles.Tile
+		ok       bool
+	}{
+		{tiles.Tile{X: 26, Y: 48, Z: 7}, 0, 0, tiles.Tile{X: 26, Y: 48, Z: 7}, true},
+		{tiles.Tile{X: 26, Y: 48, Z: 7}, 3, -2, tiles.Tile{X: 29, Y: 46, Z: 7}, true},
+		{tiles.Tile{X: 127, Y: 48, Z: 7}, 1, 0, tiles.Tile{X: 0, Y: 48, Z: 7}, true},
+		{tiles.Tile{X: 0, Y: 48, Z: 7}, -1, 0, tiles.Tile{X: 127, Y: 48, Z: 7}, true},
+		{tiles.Tile{X: 0, Y: 48, Z: 7}, -129, 0, tiles.Tile{X: 127, Y: 48, Z: 7}, true},
+		{tiles.Tile{X: 26, Y: 0, Z: 7}, 0, -1, tiles.Tile{}, false},
+		{tiles.Tile{X: 26, Y: 127, Z: 7}, 0, 1, tiles.Tile{}, false},
+	}
+	errf := "Tile%+v.Neighbor(%d, %d) -> %+v, %v"
+	for _, test := range tileTests {
+		n, ok := test.tile.Neighbor(test.dx, test.dy)
+		if n != test.neighbor || ok != test.ok {
+			t.Errorf(errf, test.tile, test.dx, test.dy, n, ok)
+		}
+	}
+}
+
+func TestTileNeighbors(t *testing.T) {
+	tileTests := []struct {
+		tile      tiles.Tile
+		neighbors []tiles.Tile
+	}{
+		{tiles.Tile{X: 0, Y: 0, Z: 0}, nil},
+		{tiles.Tile{X: 0, Y: 0, Z: 1}, []tiles.Tile{{X: 1, Y: 0, Z: 1}, {X: 1, Y: 1, Z: 1}, {X: 0, Y: 1, Z: 1}}},
+		{tiles.Tile{X: 5, Y: 0, Z: 3}, []tiles.Tile{{X: 6, Y: 0, Z: 3}, {X: 6, Y: 1, Z: 3}, {X: 5, Y: 1, Z: 3}, {X: 4, Y: 1, Z: 3}, {X: 4, Y: 0, Z: 3}}},
+		{tiles.Tile{X: 7, Y: 3, Z: 3}, []tiles.Tile{
+			{X: 7, Y: 2, Z: 3}, {X: 0, Y: 2, Z: 3}, {X: 0, Y: 3, Z: 3}, {X: 0, Y: 4, Z: 3},
+			{X: 7, Y: 4, Z: 3}, {X: 6, Y: 4, Z: 3}, {X: 6, Y: 3, Z: 3}, {X: 6, Y: 2, Z: 3},
+		}},
+	}
+	errf := "Tile%+v.Neighbors() -> %+v"
+	for _, test := range tileTests {
+		n := test.tile.Neighbors()
+		if !reflect.DeepEqual(n, test.neighbors) {
+			t.Errorf(errf, test.tile, n)
+		}
+	}
+}
+
+func TestTileFromLatLng(t *testing.T) {
+	tileTests := []struct {
+		lat, lon float64
+		zoom     int
+		tile     tiles.Tile
+	}{
+		{40.7484, -73.9857, 18, tiles.Tile{X: 77197, Y: 98526, Z: 18}},
+		{40.7484, 286.0143, 18, tiles.Tile{X: 77197, Y: 98526, Z: 18}},
+		{40.0, 180, 7, tiles.Tile{X: 0, Y: 48, Z: 7}},
+		{40.0, -185, 7, tiles.Tile{X: 126, Y: 48, Z: 7}},
+		{89.9, 10, 7, tiles.Tile{X: 67, Y: 0, Z: 7}},
+		{-89.9, 10, 7, tiles.Tile{X: 67, Y: 127, Z: 7}},
+	}
+	errf := "TileFromLatLng(%v, %v, %d) -> %+v"
+	for _, test := range tileTests {
+		tile := tiles.TileFromLatLng(test.lat, test.lon, test.zoom)
+		if tile != test.tile {
+			t.Errorf(errf, test.lat, test.lon, test.zoom, tile)
+		}
+	}
+}
+
+func TestTileFromLatLngContains(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	errf := "TileFromLatLng(%v, %v, %d) -> %v doesn't contain the point"
+	for _, p := range [][3]float64{{-79.7792, -178.50596, 12}} {
+		if tile := tiles.TileFromLatLng(p[0], p[1], int(p[2])); !tile.Contains(p[0], p[1]) {
+			t.Errorf(errf, p[0], p[1], int(p[2]), tile)
+		}
+	}
+	for i := 0; i < 10000; i++ {
+		lat, lon := rng.Float64()*170-85, rng.Float64()*360-180
+		zoom := rng.Intn(tiles.ZMax + 1)
+		if tile := tiles.TileFromLatLng(lat, lon, zoom); !tile.Contains(lat, lon) {
+			t.Errorf(errf, lat, lon, zoom, tile)
+		}
+	}
+}
+
+func TestTileFromLatLngMax(t *testing.T) {
+	coords := [][2]float64{{40.7484, -73.9857}, {40.7484, 286.0143}, {-89.9, 10}, {0, 0}}
+	errf := "TileFromLatLngMax(%v, %v) -> %+v"
+	for _, c := range coords {
+		tile := tiles.TileFromLatLngMax(c[0], c[1])
+		if tile != tiles.TileFromLatLng(c[0], c[1], tiles.ZMax) || tile.Z != tiles.ZMax {
+			t.Errorf(errf, c[0], c[1], tile)
+		}
+		// coarser tiles are the truncated quadkey of the max zoom tile
+		if qk := tile.Quadkey(); qk[:18].ToTile() != tiles.TileFromLatLng(c[0], c[1], 18) {
+			t.Errorf(errf, c[0], c[1], tile)
+		}
+	}
+}
+
+func TestTileBounds(t *testing.T) {
+	tileTests := []struct {
+		tile   tiles.Tile
+		bounds [4]float64
+	}{
+		{tiles.Tile{X: 0, Y: 0, Z: 0}, [4]float64{tiles.MinLat, tiles.MinLon, tiles.MaxLat, tiles.MaxLon}},
+		{tiles.Tile{X: 1, Y: 0, Z: 1}, [4]float64{0, 0, tiles.MaxLat, tiles.MaxLon}},
+		{tiles.Tile{X: 0, Y: 1, Z: 1}, [4]float64{tiles.MinLat, tiles.MinLon, 0, 0}},
+		{tiles.Tile{X: 26, Y: 48, Z: 7}, [4]float64{38.8225909761771, -106.875, 40.979898069620134, -104.0625}},
+	}
+	errf := "Tile%+v.Bounds() -> %v"
+	for _, test := range tileTests {
+		minLat, minLon, maxLat, maxLon := test.tile.Bounds()
+		b := [4]float64{minLat, minLon, maxLat, maxLon}
+		for i := range b {
+			if math.Abs(b[i]-test.bounds[i]) > 1e-8 {
+				t.Errorf(errf, test.tile, b)
+				break
+			}
+		}
+	}
+}
+
+func TestTileCenter(t *testing.T) {
+	tileTests := []struct {
+		tile     tiles.Tile
+		lat, lon float64
+	}{
+		{tiles.Tile{X: 0, Y: 0, Z: 0}, 0, 0},
+		{tiles.Tile{X: 1, Y: 0, Z: 1}, tiles.MaxLat / 2, 90},
+		{tiles.Tile{X: 26, Y: 48, Z: 7}, 39.901244522898364, -105.46875},
+	}
+	errf := "Tile%+v.Center() -> %v, %v"
+	for _, test := range tileTests {
+		lat, lon := test.tile.Center()
+		if math.Abs(lat-test.lat) > 1e-8 || math.Abs(lon-test.lon) > 1e-8 {
+			t.Errorf(errf, test.tile, lat, lon)
+		}
+	}
+	for _, tile := range []tiles.Tile{{X: 77197, Y: 98526, Z: 18}, {X: 26, Y: 48, Z: 7}} {
+		lat, lon := tile.Center()
+		if c := tiles.FromCoordinate(lat, lon, tile.Z); c != tile {
+			t.Errorf("Tile%+v.Center() is in %+v", tile, c)
+		}
+	}
+}
+
+func TestTileContains(t *testing.T) {
+	tile := tiles.Tile{X: 26, Y: 48, Z: 7}
+	minLat, minLon, maxLat, maxLon := tile.Bounds()
+	tileTests := []struct {
+		lat, lon float64
+		ok       bool
+	}{
+		{40.0, -105.0, true},
+		{40.7484, -73.9857, false},
+		{maxLat, minLon, true},
+		{maxLat, -105.0, true},
+		{40.0, minLon, true},
+		{minLat, -105.0, false},
+		{40.0, maxLon, false},
+		{minLat, maxLon, false},
+	}
+	errf := "Tile%+v.Contains(%v, %v) -> %v"
+	for _, test := range tileTests {
+		if ok := tile.Contains(test.lat, test.lon); ok != test.ok {
+			t.Errorf(errf, tile, test.lat, test.lon, ok)
+		}
+	}
+	if !tile.Parent().Contains(40.0, -105.0) {
+		t.Errorf("Tile%+v.Contains(40.0, -105.0) -> false", tile.Parent())
+	}
+}
+
+func TestTileString(t *testing.T) {
+	tileTests := []struct {
+		tile tiles.Tile
+		s    string
+	}{
+		{tiles.Tile{X: 0, Y: 0, Z: 0}, "0/0/0"},
+		{tiles.Tile{X: 26, Y: 48, Z: 7}, "7/26/48"},
+		{tiles.Tile{X: 77197, Y: 98526, Z: 18}, "18/77197/98526"},
+	}
+	errf := "Tile%+v: %q -> %q"
+	for _, test := range tileTests {
+		if s := test.tile.String(); s != test.s {
+			t.Errorf(errf, test.tile, test.s, s)
+		}
+		if s := fmt.Sprint(test.tile); s != test.s {
+			t.Errorf(errf, test.tile, test.s, s)
+		}
+	}
+}
+
+func TestParseTile(t *testing.T) {
+	tileTests := []struct {
+		s    string
+		tile tiles.Tile
+		ok   bool
+	}{
+		{"0/0/0", tiles.Tile{X: 0, Y: 0, Z: 0}, true},
+		{"7/26/48", tiles.Tile{X: 26, Y: 48, Z: 7}, true},
+		{"1/1/1", tiles.Tile{X: 1, Y: 1, Z: 1}, true},
+		{"1/2/1", tiles.Tile{}, false},
+		{"1/1/2", tiles.Tile{}, false},
+		{"1/-1/0", tiles.Tile{}, false},
+		{"-1/0/0", tiles.Tile{}, false},
+		{"24/0/0", tiles.Tile{}, false},
+		{"7/26", tiles.Tile{}, false},
+		{"7/26/48/1", tiles.Tile{}, false},
+		{"z/x/y", tiles.Tile{}, false},
+		{"", tiles.Tile{}, false},
+	}
+	errf := "ParseTile(%q) -> %+v, %v"
+	for _, test := range tileTests {
+		tile, err := tiles.ParseTile(test.s)
+		if tile != test.tile || (err == nil) != test.ok {
+			t.Errorf(errf, test.s, tile, err)
+		}
+	}
+}
+
+func TestTileDistanceTo(t *testing.T) {
+	a := tiles.Tile{X: 26, Y: 48, Z: 7}
+	if d := a.DistanceTo(a); d != 0 {
+		t.Errorf("Tile%+v.DistanceTo(self) -> %v", a, d)
+	}
+	b := tiles.Tile{X: 27, Y: 48, Z: 7}
+	lat, lon := a.Center()
+	lat2, lon2 := b.Center()
+	if d, exp := a.DistanceTo(b), tiles.DistanceMeters(lat, lon, lat2, lon2); d != exp || d != b.DistanceTo(a) {
+		t.Errorf("Tile%+v.DistanceTo(%+v) -> %v", a, b, d)
+	}
+}
+
+func TestTileSubTileAtPixel(t *testing.T) {
+	den := tiles.Tile{X: 26, Y: 48, Z: 7}
+	tests := []struct {
+		px, py, dz int
+		tile       tiles.Tile
+	}{
+		{0, 0, 1, tiles.Tile{X: 52, Y: 96, Z: 8}},
+		{128, 0, 1, tiles.Tile{X: 53, Y: 96, Z: 8}},
+		{127, 128, 1, tiles.Tile{X: 52, Y: 97, Z: 8}},
+		{256, 256, 1, tiles.Tile{X: 53, Y: 97, Z: 8}},
+		{-5, 300, 2, tiles.Tile{X: 104, Y: 195, Z: 9}},
+		{64, 192, 2, tiles.Tile{X: 105, Y: 195, Z: 9}},
+		{255, 0, 8, tiles.Tile{X: 26<<8 + 255, Y: 48 << 8, Z: 15}},
+		{100, 100, 0, den},
+		{100, 100, -1, den},
+		{256, 256, 30, tiles.Tile{X: 27<<16 - 256, Y: 49<<16 - 256, Z: tiles.ZMax}},
+	}
+	errf := "Tile%+v.SubTileAtPixel(%d, %d, %d) -> %+v"
+	for _, test := range tests {
+		sub := den.SubTileAtPixel(test.px, test.py, test.dz)
+		if sub != test.tile || !den.ContainsTile(sub) && sub != den {
+			t.Errorf(errf, den, test.px, test.py, test.dz, sub)
+		}
+	}
+}
+
+func TestTileBearingTo(t *testing.T) {
+	a := tiles.Tile{X: 26, Y: 48, Z: 7}
+	tests := []struct {
+		other tiles.Tile
+		deg   float64
+	}{
+		{a, 0},
+		{tiles.Tile{X: 26, Y: 47, Z: 7}, 0},
+		{tiles.Tile{X: 26, Y: 49, Z: 7}, 180},
+	}
+	errf := "Tile%+v.BearingTo(%+v) -> %v"
+	for _, test := range tests {
+		if deg := a.BearingTo(test.other); deg != test.deg {
+			t.Errorf(errf, a, test.other, deg)
+		}
+	}
+	b := tiles.Tile{X: 27, Y: 48, Z: 7}
+	lat, lon := a.Center()
+	lat2, lon2 := b.Center()
+	if deg := a.BearingTo(b); deg != tiles.BearingDegrees(lat, lon, lat2, lon2) || deg <= 0 || deg >= 180 {
+		t.Errorf(errf, a, b, deg)
+	}
+}
+
+func TestTileToUint64(t *testing.T) {
+	tileTests := []struct {
+		tile tiles.Tile
+		u    uint64
+	}{
+		{tiles.Tile{X: 0, Y: 0, Z: 0}, 0},
+		{tiles.Tile{X: 1, Y: 0, Z: 1}, 1<<58 | 1<<29},
+		{tiles.Tile{X: 0, Y: 1, Z: 1}, 1<<58 | 1},
+		{tiles.Tile{X: 26, Y: 48, Z: 7}, 7<<58 | 26<<29 | 48},
+		{tiles.Tile{X: 1<<23 - 1, Y: 1<<23 - 1, Z: 23}, 23<<58 | (1<<23-1)<<29 | (1<<23 - 1)},
+		{tiles.Tile{X: 1<<29 - 1, Y: 1<<29 - 1, Z: 29}, 29<<58 | (1<<29-1)<<29 | (1<<29 - 1)},
+	}
+	errf := "Tile%+v: %d -> %d"
+	for _, test := range tileTests {
+		u := test.tile.ToUint64()
+		if u != test.u {
+			t.Errorf(errf, test.tile, test.u, u)
+		}
+		if tile := tiles.TileFromUint64(u); tile != test.tile {
+			t.Errorf("TileFromUint64(%d) -> %+v", u, tile)
+		}
+	}
+	seen := make(map[uint64]tiles.Tile)
+	for z := 0; z <= 5; z++ {
+		for x := 0; x < 1<<uint(z); x++ {
+			for y := 0; y < 1<<uint(z); y++ {
+				tile := tiles.Tile{X: x, Y: y, Z: z}
+				u := tile.ToUint64()
+				if o, ok := seen[u]; ok {
+					t.Errorf("Tile%+v and Tile%+v both pack to %d", tile, o, u)
+				}
+				seen[u] = tile
+				if tiles.TileFromUint64(u) != tile {
+					t.Errorf("TileFromUint64(%d) -> %+v", u, tiles.TileFromUint64(u))
+				}
+			}
+		}
+	}
+}
+
+func TestTileBuffered(t *testing.T) {
+	den := tiles.Tile{X: 26, Y: 48, Z: 7}
+	// a degree of latitude on the EarthRadiusM sphere
+	degree := tiles.EarthRadiusM * math.Pi / 180
+	tileTests := []struct {
+		tile   tiles.Tile
+		meters float64
+		bbox   [4]float64
+	}{
+		{den, 0, [4]float64{38.8225909761771, -106.875, 40.979898069620134, -104.0625}},
+		{den, -5, [4]float64{38.8225909761771, -106.875, 40.979898069620134, -104.0625}},
+		{den, degree, [4]float64{37.8225909761771, -108.19965984668825, 41.979898069620134, -102.73784015331175}},
+		{tiles.Tile{X: 0, Y: 100, Z: 8}, 1000, [4]float64{35.4516867986541, 179.9888107728542, 36.6068722859114, -178.5825607728542}},
+		{tiles.Tile{X: 1, Y: 0, Z: 2}, 1000, [4]float64{66.50427729027065, -90.10413234490252, tiles.MaxLat, 0.10413234490252878}},
+		{tiles.Tile{}, 1000, [4]float64{tiles.MinLat, tiles.MinLon, tiles.MaxLat, tiles.MaxLon}},
+	}
+	errf := "Tile%+v.Buffered(%v) -> %v"
+	for _, test := range tileTests {
+		minLat, minLon, maxLat, maxLon := test.tile.Buffered(test.meters)
+		b := [4]float64{minLat, minLon, maxLat, maxLon}
+		for i := range b {
+			if math.Abs(b[i]-test.bbox[i]) > 1e-8 {
+				t.Errorf(errf, test.tile, test.meters, b)
+				break
+			}
+		}
+	}
+	minLat, minLon, maxLat, maxLon := den.Buffered(1000)
+	if covered := tiles.TilesForBBox(minLat, minLon, maxLat, maxLon, den.Z); len(covered) != 9 {
+		t.Error("TilesForBBox(Buffered) didn't cover the neighbors: ", covered)
+	}
+}
+
+func TestTileIntersectBBox(t *testing.T) {
+	// 7/26/48 spans 38.8225909761771, -106.875 to 40.979898069620134, -104.0625
+	den := tiles.Tile{X: 26, Y: 48, Z: 7}
+	tileTests := []struct {
+		tile                           tiles.Tile
+		minLat, minLon, maxLat, maxLon float64
+		bbox                           [4]float64
+		ok                             bool
+	}{
+		{den, 39, -106, 40, -105, [4]float64{39, -106, 40, -105}, true},
+		{den, 30, -110, 50, -100, [4]float64{38.8225909761771, -106.875, 40.979898069620134, -104.0625}, true},
+		{den, 40, -105, 50, -100, [4]float64{40, -105, 40.979898069620134, -104.0625}, true},
+		{den, 30, -110, 39, -106, [4]float64{38.8225909761771, -106.875, 39, -106}, true},
+		{den, 41, -106, 50, -105, [4]float64{}, false},
+		{den, 39, -104.0625, 40, -100, [4]float64{}, false},
+		{den, 0, 0, 10, 10, [4]float64{}, false},
+		{tiles.Tile{}, -10, -10, 10, 10, [4]float64{-10, -10, 10, 10}, true},
+	}
+	errf := "%+v.IntersectBBox(%v, %v, %v, %v) -> %v, %v, %v, %v, %t"
+	for _, test := range tileTests {
+		minLat, minLon, maxLat, maxLon, ok := test.tile.IntersectBBox(test.minLat, test.minLon, test.maxLat, test.maxLon)
+		if [4]float64{minLat, minLon, maxLat, maxLon} != test.bbox || ok != test.ok {
+			t.Errorf(errf, test.tile, test.minLat, test.minLon, test.maxLat, test.maxLon, minLat, minLon, maxLat, maxLon, ok)
+		}
+	}
+}
+
+func TestTileBoundsMeters(t *testing.T) {
+	const extent = 20037508.342789244
+	tileTests := []struct {
+		tile                   tiles.Tile
+		minX, minY, maxX, maxY float64
+	}{
+		{tiles.Tile{X: 0, Y: 0, Z: 0}, -extent, -extent, extent, extent},
+		{tiles.Tile{X: 1, Y: 0, Z: 1}, 0, 0, extent, extent},
+		{tiles.Tile{X: 0, Y: 1, Z: 1}, -extent, -extent, 0, 0},
+		{tiles.Tile{X: 26, Y: 48, Z: 7}, -11897270.578531113, 4696291.017841229, -11584184.510675031, 5009377.085697311},
+	}
+	errf := "%+v.BoundsMeters() -> %v, %v, %v, %v"
+	for _, test := range tileTests {
+		minX, minY, maxX, maxY := test.tile.BoundsMeters()
+		if math.Abs(minX-test.minX) > 1e-6 || math.Abs(minY-test.minY) > 1e-6 || math.Abs(maxX-test.maxX) > 1e-6 || math.Abs(maxY-test.maxY) > 1e-6 {
+			t.Errorf(errf, test.tile, minX, minY, maxX, maxY)
+		}
+		// the center of the extent maps back to the tile
+		if tile := tiles.TileFromMeters((minX+maxX)/2, (minY+maxY)/2, test.tile.Z); tile != test.tile {
+			t.Errorf("TileFromMeters(%+v center) -> %+v", test.tile, tile)
+		}
+	}
+	meterTests := []struct {
+		x, y float64
+		zoom int
+		tile tiles.Tile
+	}{
+		{0, 0, 1, tiles.Tile{X: 1, Y: 1, Z: 1}},
+		{-1, 1, 1, tiles.Tile{X: 0, Y: 0, Z: 1}},
+		{-extent, extent, 4, tiles.Tile{X: 0, Y: 0, Z: 4}},
+		{extent, -extent, 4, tiles.Tile{X: 15, Y: 15, Z: 4}},
+		{3 * extent, -3 * extent, 4, tiles.Tile{X: 15, Y: 15, Z: 4}},
+		{-8236050.45, 4975301.25, 18, tiles.FromCoordinate(40.7484, -73.9857, 18)},
+	}
+	errf = "TileFromMeters(%v, %v, %d) -> %+v"
+	for _, test := range meterTests {
+		if tile := tiles.TileFromMeters(test.x, test.y, test.zoom); tile != test.tile {
+			t.Errorf(errf, test.x, test.y, test.zoom, tile)
+		}
+	}
+}
+
+func TestTileCorners(t *testing.T) {
+	tileTests := []struct {
+		tile    tiles.Tile
+		corners [4][2]float64
+	}{
+		{tiles.Tile{}, [4][2]float64{{tiles.MaxLat, tiles.MinLon}, {tiles.MaxLat, tiles.MaxLon}, {tiles.MinLat, tiles.MaxLon}, {tiles.MinLat, tiles.MinLon}}},
+		{tiles.Tile{X: 26, Y: 48, Z: 7}, [4][2]float64{{40.979898069620134, -106.875}, {40.979898069620134, -104.0625}, {38.8225909761771, -104.0625}, {38.8225909761771, -106.875}}},
+	}
+	errf := "%+v.Corners() -> %v"
+	for _, test := range tileTests {
+		corners := test.tile.Corners()
+		for i := range corners {
+			if math.Abs(corners[i][0]-test.corners[i][0]) > 1e-8 || math.Abs(corners[i][1]-test.corners[i][1]) > 1e-8 {
+				t.Errorf(errf, test.tile, corners)
+				break
+			}
+		}
+	}
+}
+
+func TestTileWKT(t *testing.T) {
+	tileTests := []struct {
+		tile tiles.Tile
+		wkt  string
+	}{
+		{
+			tiles.Tile{X: 1, Y: 0, Z: 1},
+			"POLYGON((0 0, 180 0, 180 85.05112877980659, 0 85.05112877980659, 0 0))",
+		},
+		{
+			tiles.Tile{X: 26, Y: 48, Z: 7},
+			"POLYGON((-106.875 38.8225909761771, -104.0625 38.8225909761771, -104.0625 40.979898069620134, -106.875 40.979898069620134, -106.875 38.8225909761771))",
+		},
+	}
+	errf := "%+v.WKT() -> %s"
+	for _, test := range tileTests {
+		if wkt := test.tile.WKT(); wkt != test.wkt {
+			t.Errorf(errf, test.tile, wkt)
+		}
+	}
+}
+
+func TestTileTMS(t *testing.T) {
+	tileTests := []struct {
+		tile    tiles.Tile
+		x, y, z int
+	}{
+		{tiles.Tile{X: 0, Y: 0, Z: 0}, 0, 0, 0},
+		{tiles.Tile{X: 1, Y: 0, Z: 1}, 1, 1, 1},
+		{tiles.Tile{X: 0, Y: 1, Z: 1}, 0, 0, 1},
+		{tiles.Tile{X: 26, Y: 48, Z: 7}, 26, 79, 7},
+		{tiles.Tile{X: 77197, Y: 98526, Z: 18}, 77197, 163617, 18},
+	}
+	errf := "%+v.TMS() -> %d, %d, %d"
+	for _, test := range tileTests {
+		if x, y, z := test.tile.TMS(); x != test.x || y != test.y || z != test.z {
+			t.Errorf(errf, test.tile, x, y, z)
+		}
+		if tile := tiles.TileFromTMS(test.x, test.y, test.z); tile != test.tile {
+			t.Errorf("TileFromTMS(%d, %d, %d) -> %+v", test.x, test.y, test.z, tile)
+		}
+	}
+	for z := 0; z <= tiles.ZMax; z++ {
+		n := 1 << uint(z)
+		for _, tile := range []tiles.Tile{{X: 0, Y: 0, Z: z}, {X: n - 1, Y: n - 1, Z: z}, {X: n / 2, Y: n / 3, Z: z}} {
+			if rt := tiles.TileFromTMS(tile.TMS()); rt != tile {
+				t.Errorf("TileFromTMS(%+v.TMS()) -> %+v", tile, rt)
+			}
+		}
+	}
+}
+
+func TestTileResolutionMeters(t *testing.T) {
+	tileTests := []struct {
+		tile tiles.Tile
+		res  float64
+	}{
+		{tiles.Tile{X: 0, Y: 0, Z: 0}, 156543.03392804097},
+		{tiles.Tile{X: 0, Y: 0, Z: 1}, 57684.21592},
+		{tiles.Tile{X: 26, Y: 48, Z: 7}, 938.22015},
+		{tiles.Tile{X: 77197, Y: 98526, Z: 18}, 0.45240},
+	}
+	errf := "%+v.ResolutionMeters() -> %v"
+	for _, test := range tileTests {
+		if res := test.tile.ResolutionMeters(); math.Abs(res-test.res) > 1e-5 {
+			t.Errorf(errf, test.tile, res)
+		}
+	}
+}
+
+func TestTileMorton(t *testing.T) {
+	tileTests := []struct {
+		tile tiles.Tile
+		code uint64
+	}{
+		{tiles.Tile{}, 0},
+		{tiles.Tile{X: 1, Y: 0, Z: 1}, 1},
+		{tiles.Tile{X: 0, Y: 1, Z: 1}, 2},
+		{tiles.Tile{X: 1, Y: 1, Z: 2}, 3},
+		{tiles.Tile{X: 3, Y: 5, Z: 3}, 0x27},
+		{tiles.Tile{X: 26, Y: 48, Z: 7}, 0xb44},
+		{tiles.Tile{X: 1<<23 - 1, Y: 1<<23 - 1, Z: 23}, 1<<46 - 1},
+	}
+	errf := "%+v.Morton() -> %#x"
+	for _, test := range tileTests {
+		if code := test.tile.Morton(); code != test.code {
+			t.Errorf(errf, test.tile, code)
+		}
+		if tile := tiles.TileFromMorton(test.code, test.tile.Z); tile != test.tile {
+			t.Errorf("TileFromMorton(%#x, %d) -> %+v", test.code, test.tile.Z, tile)
+		}
+		// the code is the quadkey read as a base 4 number
+		if qk, err := strconv.ParseUint("0"+string(test.tile.Quadkey()), 4, 64); err != nil || qk != test.code {
+			t.Errorf("%+v.Quadkey() base 4 -> %#x, %v", test.tile, qk, err)
+		}
+	}
+}
+
+func TestTileLess(t *testing.T) {
+	den := tiles.Tile{X: 26, Y: 48, Z: 7}
+	tileTests := []struct {
+		a, b        tiles.Tile
+		equal, less bool
+	}{
+		{den, den, true, false},
+		{den, den.Children()[0], false, true},
+		{den.Children()[0], den, false, false},
+		{den.Children()[0], den.Children()[1], false, true},
+		{den.Children()[3], tiles.Tile{X: 27, Y: 48, Z: 7}, false, true},
+		{tiles.Tile{}, den, false, true},
+		{tiles.Tile{X: 1, Y: 0, Z: 1}, tiles.Tile{X: 0, Y: 1, Z: 1}, false, true},
+	}
+	errf := "%+v.%s(%+v) -> %t"
+	for _, test := range tileTests {
+		if eq := test.a.Equal(test.b); eq != test.equal {
+			t.Errorf(errf, test.a, "Equal", test.b, eq)
+		}
+		if less := test.a.Less(test.b); less != test.less {
+			t.Errorf(errf, test.a, "Less", test.b, less)
+		}
+		if less := test.a.Less(test.b); less != (test.a.Quadkey() < test.b.Quadkey()) {
+			t.Errorf(errf, test.a, "Less", test.b, less)
+		}
+	}
+}
+
+func TestTileContainsTile(t *testing.T) {
+	den := tiles.Tile{X: 26, Y: 48, Z: 7}
+	tileTests := []struct {
+		t, other          tiles.Tile
+		contains, overlap bool
+	}{
+		{den, den, true, true},
+		{den, den.Children()[2], true, true},
+		{den, den.Children()[2].Children()[1], true, true},
+		{den.Children()[2], den, false, true},
+		{tiles.Tile{}, den, true, true},
+		{den, tiles.Tile{X: 27, Y: 48, Z: 7}, false, false},
+		{den, tiles.Tile{X: 54, Y: 96, Z: 8}, false, false},
+		{den.Children()[0], den.Children()[3], false, false},
+	}
+	errf := "%+v.%s(%+v) -> %t"
+	for _, test := range tileTests {
+		if c := test.t.ContainsTile(test.other); c != test.contains {
+			t.Errorf(errf, test.t, "ContainsTile", test.other, c)
+		}
+		if o := test.t.Overlaps(test.other); o != test.overlap {
+			t.Errorf(errf, test.t, "Overlaps", test.other, o)
+		}
+		if o := test.other.Overlaps(test.t); o != test.overlap {
+			t.Errorf(errf, test.other, "Overlaps", test.t, o)
+		}
+	}
+}
+
+func TestTileChildrenAtZoom(t *testing.T) {
+	den := tiles.Tile{X: 26, Y: 48, Z: 7}
+	tileTests := []struct {
+		tile     tiles.Tile
+		zoom     int
+		children []tiles.Tile
+	}{
+		{den, 7, nil},
+		{den, 6, nil},
+		{den, 8, []tiles.Tile{{X: 52, Y: 96, Z: 8}, {X: 53, Y: 96, Z: 8}, {X: 52, Y: 97, Z: 8}, {X: 53, Y: 97, Z: 8}}},
+		{tiles.Tile{}, 1, []tiles.Tile{{X: 0, Y: 0, Z: 1}, {X: 1, Y: 0, Z: 1}, {X: 0, Y: 1, Z: 1}, {X: 1, Y: 1, Z: 1}}},
+		{tiles.Tile{X: 1, Y: 1, Z: 22}, tiles.ZMax + 1, nil},
+	}
+	errf := "%+v.ChildrenAtZoom(%d) -> %v"
+	for _, test := range tileTests {
+		if c := test.tile.ChildrenAtZoom(test.zoom); !reflect.DeepEqual(c, test.children) {
+			t.Errorf(errf, test.tile, test.zoom, c)
+		}
+	}
+	children := den.ChildrenAtZoom(10)
+	if len(children) != 64 {
+		t.Errorf(errf, den, 10, children)
+	}
+	seen := make(map[tiles.Tile]bool)
+	for _, c := range children {
+		if seen[c] || !den.ContainsTile(c) || c.Z != 10 {
+			t.Errorf(errf, den, 10, children)
+		}
+		seen[c] = true
+	}
+}
+
+func TestTileAtZoom(t *testing.T) {
+	den := tiles.Tile{X: 26, Y: 48, Z: 7}
+	tileTests := []struct {
+		tile tiles.Tile
+		zoom int
+		at   tiles.Tile
+	}{
+		{den, 7, den},
+		{den, 6, tiles.Tile{X: 13, Y: 24, Z: 6}},
+		{den, 0, tiles.Tile{}},
+		{den, -1, tiles.Tile{}},
+		{den, 8, tiles.Tile{X: 52, Y: 96, Z: 8}},
+		{den, 10, tiles.Tile{X: 208, Y: 384, Z: 10}},
+		{tiles.Tile{X: 1, Y: 1, Z: 22}, tiles.ZMax + 1, tiles.Tile{X: 2, Y: 2, Z: tiles.ZMax}},
+	}
+	errf := "%+v.AtZoom(%d) -> %v"
+	for _, test := range tileTests {
+		if at := test.tile.AtZoom(test.zoom); at != test.at {
+			t.Errorf(errf, test.tile, test.zoom, at)
+		}
+	}
+	// the descendant's quadkey extends the tile's with 0s and the ancestor's is a prefix of it
+	if qk := den.AtZoom(10).Quadkey(); qk != den.Quadkey()+"000" || den.AtZoom(4).Quadkey() != den.Quadkey()[:4] {
+		t.Errorf(errf, den, 10, qk)
+	}
+}
+
+func TestTileAncestors(t *testing.T) {
+	esb := tiles.FromCoordinate(40.7484, -73.9857, 18)
+	nyc := tiles.Tile{X: 75, Y: 96, Z: 8}
+	tileTests := []struct {
+		tile      tiles.Tile
+		zoom      int
+		ancestors []tiles.Tile
+	}{
+		{esb, 18, nil},
+		{esb, 20, nil},
+		{esb, 17, []tiles.Tile{esb.Parent()}},
+		{esb, 15, []tiles.Tile{esb.Parent(), esb.Parent().Parent(), esb.Parent().Parent().Parent()}},
+		{nyc, 6, []tiles.Tile{{X: 37, Y: 48, Z: 7}, {X: 18, Y: 24, Z: 6}}},
+		{nyc.Children()[0], -1, []tiles.Tile{nyc, {X: 37, Y: 48, Z: 7}, {X: 18, Y: 24, Z: 6}, {X: 9, Y: 12, Z: 5}, {X: 4, Y: 6, Z: 4}, {X: 2, Y: 3, Z: 3}, {X: 1, Y: 1, Z: 2}, {X: 0, Y: 0, Z: 1}, {}}},
+		{tiles.Tile{}, 0, nil},
+	}
+	errf := "%+v.Ancestors(%d) -> %v"
+	for _, test := range tileTests {
+		if a := test.tile.Ancestors(test.zoom); !reflect.DeepEqual(a, test.ancestors) {
+			t.Errorf(errf, test.tile, test.zoom, a)
+		}
+	}
+}
+
+func TestTileQuadrant(t *testing.T) {
+	den := tiles.Tile{X: 26, Y: 48, Z: 7}
+	tileTests := []struct {
+		tile     tiles.Tile
+		quadrant int
+		siblings [3]tiles.Tile
+	}{
+		{tiles.Tile{}, 0, [3]tiles.Tile{}},
+		{den.Children()[0], 0, [3]tiles.Tile{den.Children()[1], den.Children()[2], den.Children()[3]}},
+		{den.Children()[1], 1, [3]tiles.Tile{den.Children()[0], den.Children()[2], den.Children()[3]}},
+		{den.Children()[2], 2, [3]tiles.Tile{den.Children()[0], den.Children()[1], den.Children()[3]}},
+		{den.Children()[3], 3, [3]tiles.Tile{den.Children()[0], den.Children()[1], den.Children()[2]}},
+		{tiles.Tile{X: 1, Y: 0, Z: 1}, 1, [3]tiles.Tile{{X: 0, Y: 0, Z: 1}, {X: 0, Y: 1, Z: 1}, {X: 1, Y: 1, Z: 1}}},
+	}
+	errf := "%+v.%s() -> %v"
+	for _, test := range tileTests {
+		if q := test.tile.Quadrant(); q != test.quadrant {
+			t.Errorf(errf, test.tile, "Quadrant", q)
+		}
+		if qk := test.tile.Quadkey(); len(qk) > 0 && int(qk[len(qk)-1]-'0') != test.quadrant {
+			t.Errorf(errf, test.tile, "Quadkey", qk)
+		}
+		if s := test.tile.Siblings(); s != test.siblings {
+			t.Errorf(errf, test.tile, "Siblings", s)
+		}
+	}
+}
+
+func TestCommonAncestor(t *testing.T) {
+	esb := tiles.FromCoordinate(40.7484, -73.9857, 18)
+	sol := tiles.FromCoordinate(40.6892, -74.0445, 18)
+	bbn := tiles.FromCoordinate(51.5007, -0.1246, 18)
+	den := tiles.Tile{X: 26, Y: 48, Z: 7}
+	tileTests := []struct {
+		a, b     tiles.Tile
+		ancestor tiles.Tile
+	}{
+		{esb, esb, esb},
+		{esb, sol, tiles.Tile{X: 150, Y: 192, Z: 9}},
+		{esb, bbn, tiles.Tile{X: 1, Y: 1, Z: 2}},
+		{esb, tiles.Tile{X: 1, Y: 1, Z: 1}, tiles.Tile{}},
+		{esb, esb.Parent(), esb.Parent()},
+		{den, den.Children()[3], den},
+		{tiles.Tile{}, esb, tiles.Tile{}},
+	}
+	errf := "CommonAncestor(%+v, %+v) -> %+v"
+	for _, test := range tileTests {
+		if a := tiles.CommonAncestor(test.a, test.b); a != test.ancestor {
+			t.Errorf(errf, test.a, test.b, a)
+		}
+		if a := tiles.CommonAncestor(test.b, test.a); a != test.ancestor {
+			t.Errorf(errf, test.b, test.a, a)
+		}
+	}
+}
+
 var (
 	// These are globals to make sure that the compiler doesn't skip benchmarks
 	bT tiles.Tile
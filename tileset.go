@@ -0,0 +1,134 @@
+package tiles
+
+import "sort"
+
+// TileSet is a set of tiles where a tile covers all of its descendants.
+// It's kept minimal, a tile isn't stored if an ancestor is and adding a tile drops its stored descendants,
+// so the set operations work on the area covered rather than the exact tiles added.
+// The zero value is an empty set ready to use, but a TileSet isn't safe for concurrent writes
+type TileSet struct {
+	qks map[Quadkey]struct{}
+}
+
+// NewTileSet returns a TileSet covering the tiles
+func NewTileSet(tiles ...Tile) *TileSet {
+	s := &TileSet{qks: make(map[Quadkey]struct{}, len(tiles))}
+	for _, t := range tiles {
+		s.Add(t)
+	}
+	return s
+}
+
+// Add covers the tile. It's a no-op if the tile is already covered
+func (s *TileSet) Add(t Tile) {
+	s.add(t.Quadkey())
+}
+
+func (s *TileSet) add(qk Quadkey) {
+	if s.covers(qk) {
+		return
+	}
+	if s.qks == nil {
+		s.qks = make(map[Quadkey]struct{})
+	}
+	for k := range s.qks {
+		if k.HasParent(qk) {
+			delete(s.qks, k)
+		}
+	}
+	s.qks[qk] = struct{}{}
+}
+
+// Contains returns true if the tile or one of its ancestors is in the set
+func (s *TileSet) Contains(t Tile) bool {
+	return s.covers(t.Quadkey())
+}
+
+func (s *TileSet) covers(qk Quadkey) bool {
+	for z := 0; z <= len(qk); z++ {
+		if _, ok := s.qks[qk[:z]]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// under returns true if the set has a tile below qk
+func (s *TileSet) under(qk Quadkey) bool {
+	for k := range s.qks {
+		if k.HasParent(qk) {
+			return true
+		}
+	}
+	return false
+}
+
+// Len returns the number of tiles stored, the size of Slice
+func (s *TileSet) Len() int {
+	return len(s.qks)
+}
+
+// Union returns a new set covering the tiles in either set
+func (s *TileSet) Union(other *TileSet) *TileSet {
+	u := &TileSet{qks: make(map[Quadkey]struct{}, len(s.qks)+len(other.qks))}
+	for qk := range s.qks {
+		u.add(qk)
+	}
+	for qk := range other.qks {
+		u.add(qk)
+	}
+	return u
+}
+
+// Intersect returns a new set covering the area that's in both sets.
+// Where a tile in one set is covered by a shallower tile in the other, the deeper tile is kept
+func (s *TileSet) Intersect(other *TileSet) *TileSet {
+	i := &TileSet{qks: make(map[Quadkey]struct{})}
+	for qk := range s.qks {
+		if other.covers(qk) {
+			i.qks[qk] = struct{}{}
+		}
+	}
+	for qk := range other.qks {
+		if s.covers(qk) {
+			i.qks[qk] = struct{}{}
+		}
+	}
+	return i
+}
+
+// Difference returns a new set covering the area in s that isn't in other.
+// A tile in s that's only partly covered by other is split into the children that aren't
+func (s *TileSet) Difference(other *TileSet) *TileSet {
+	d := &TileSet{qks: make(map[Quadkey]struct{})}
+	var subtract func(qk Quadkey)
+	subtract = func(qk Quadkey) {
+		switch {
+		case other.covers(qk):
+		case other.under(qk):
+			for _, c := range qk.Children() {
+				subtract(c)
+			}
+		default:
+			d.qks[qk] = struct{}{}
+		}
+	}
+	for qk := range s.qks {
+		subtract(qk)
+	}
+	return d
+}
+
+// Slice returns the tiles in the set in quadkey order
+func (s *TileSet) Slice() []Tile {
+	qks := make([]string, 0, len(s.qks))
+	for qk := range s.qks {
+		qks = append(qks, string(qk))
+	}
+	sort.Strings(qks)
+	tiles := make([]Tile, len(qks))
+	for i, qk := range qks {
+		tiles[i] = Quadkey(qk).ToTile()
+	}
+	return tiles
+}
@@ -0,0 +1,105 @@
+package tiles
+
+import (
+	"testing"
+)
+
+func qkTiles(qks ...string) (tiles []Tile) {
+	for _, qk := range qks {
+		tiles = append(tiles, Quadkey(qk).ToTile())
+	}
+	return
+}
+
+func TestTileSetAdd(t *testing.T) {
+	tests := []struct {
+		add   []string
+		tiles []Tile
+	}{
+		{nil, nil},
+		{[]string{"0", "1"}, qkTiles("0", "1")},
+		{[]string{"01", "0"}, qkTiles("0")},
+		{[]string{"0", "01", "012"}, qkTiles("0")},
+		{[]string{"0123", "02", "0122", "1"}, qkTiles("0122", "0123", "02", "1")},
+		{[]string{"023", "1", ""}, qkTiles("")},
+	}
+	errf := "TileSet.Add(%v) -> %v"
+	for _, test := range tests {
+		s := &TileSet{}
+		for _, tile := range qkTiles(test.add...) {
+			s.Add(tile)
+		}
+		if tiles := s.Slice(); !tileSliceEqual(tiles, test.tiles) || s.Len() != len(test.tiles) {
+			t.Errorf(errf, test.add, tiles)
+		}
+	}
+}
+
+func TestTileSetContains(t *testing.T) {
+	s := NewTileSet(qkTiles("02", "1230")...)
+	tests := []struct {
+		qk       string
+		contains bool
+	}{
+		{"02", true},
+		{"021", true},
+		{"0213", true},
+		{"1230", true},
+		{"12301", true},
+		{"0", false},
+		{"123", false},
+		{"03", false},
+		{"", false},
+	}
+	errf := "TileSet.Contains(%q) -> %t"
+	for _, test := range tests {
+		if c := s.Contains(Quadkey(test.qk).ToTile()); c != test.contains {
+			t.Errorf(errf, test.qk, c)
+		}
+	}
+}
+
+func TestTileSetOps(t *testing.T) {
+	tests := []struct {
+		a, b                []string
+		union, inter, minus []Tile
+	}{
+		{
+			[]string{"0", "1"}, []string{"1", "2"},
+			qkTiles("0", "1", "2"), qkTiles("1"), qkTiles("0"),
+		},
+		{
+			[]string{"0"}, []string{"01", "02", "3"},
+			qkTiles("0", "3"), qkTiles("01", "02"), qkTiles("00", "03"),
+		},
+		{
+			[]string{"01", "02", "3"}, []string{"0"},
+			qkTiles("0", "3"), qkTiles("01", "02"), qkTiles("3"),
+		},
+		{
+			[]string{"1"}, []string{"1023"},
+			qkTiles("1"), qkTiles("1023"), qkTiles("100", "101", "1020", "1021", "1022", "103", "11", "12", "13"),
+		},
+		{
+			[]string{"0"}, []string{"1"},
+			qkTiles("0", "1"), nil, qkTiles("0"),
+		},
+		{
+			[]string{"0"}, nil,
+			qkTiles("0"), nil, qkTiles("0"),
+		},
+	}
+	errf := "%v.%s(%v) -> %v"
+	for _, test := range tests {
+		a, b := NewTileSet(qkTiles(test.a...)...), NewTileSet(qkTiles(test.b...)...)
+		if tiles := a.Union(b).Slice(); !tileSliceEqual(tiles, test.union) {
+			t.Errorf(errf, test.a, "Union", test.b, tiles)
+		}
+		if tiles := a.Intersect(b).Slice(); !tileSliceEqual(tiles, test.inter) {
+			t.Errorf(errf, test.a, "Intersect", test.b, tiles)
+		}
+		if tiles := a.Difference(b).Slice(); !tileSliceEqual(tiles, test.minus) {
+			t.Errorf(errf, test.a, "Difference", test.b, tiles)
+		}
+	}
+}
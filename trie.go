@@ -0,0 +1,119 @@
+package tiles
+
+import (
+	"context"
+	"sync"
+)
+
+// TrieIndex is a TileIndex implementation that uses a quadtree trie keyed by quadkey digits.
+// Aggregating values up is a walk of the subtree under the requested tile, so it suits write-once, query-many workloads.
+// TrieIndex is thread safe
+type TrieIndex struct {
+	root trieNode
+	sync.RWMutex
+}
+
+type trieNode struct {
+	children [4]*trieNode
+	values   []interface{}
+}
+
+// NewTrieIndex returns an empty TrieIndex
+func NewTrieIndex() *TrieIndex {
+	return &TrieIndex{}
+}
+
+// TileRange returns a channel of all tiles in the index in the zoom range
+// Each tile is emitted once, before all of the tiles under it.
+// Acquires a readlock for duration of returned channel being open
+func (idx *TrieIndex) TileRange(zmin, zmax int) <-chan Tile {
+	return idx.TileRangeContext(context.Background(), zmin, zmax)
+}
+
+// TileRangeContext is TileRange that stops sending and closes the channel when ctx is done.
+func (idx *TrieIndex) TileRangeContext(ctx context.Context, zmin, zmax int) <-chan Tile {
+	tiles := make(chan Tile, 1<<10)
+	go func() {
+		defer close(tiles)
+		idx.RLock()
+		defer idx.RUnlock()
+		idx.root.walk(Tile{}, zmin, zmax, func(t Tile) bool {
+			select {
+			case tiles <- t:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		})
+	}()
+	return tiles
+}
+
+// Values returns a list of values aggregated under the requested tile
+func (idx *TrieIndex) Values(t Tile) (vals []interface{}) {
+	idx.RLock()
+	defer idx.RUnlock()
+	n := idx.root.find(t.Quadkey())
+	if n != nil {
+		vals = n.collect(vals)
+	}
+	return
+}
+
+// Add adds values to the tile
+func (idx *TrieIndex) Add(t Tile, val ...interface{}) {
+	idx.Lock()
+	defer idx.Unlock()
+	n := &idx.root
+	for _, d := range []byte(t.Quadkey()) {
+		c := d - '0'
+		if n.children[c] == nil {
+			n.children[c] = &trieNode{}
+		}
+		n = n.children[c]
+	}
+	n.values = append(n.values, val...)
+}
+
+// find returns the node at the quadkey or nil if nothing is stored under it
+func (n *trieNode) find(qk Quadkey) *trieNode {
+	for _, d := range []byte(qk) {
+		n = n.children[d-'0']
+		if n == nil {
+			return nil
+		}
+	}
+	return n
+}
+
+// collect appends all of the values in the subtree in quadkey order
+func (n *trieNode) collect(vals []interface{}) []interface{} {
+	vals = append(vals, n.values...)
+	for _, c := range n.children {
+		if c != nil {
+			vals = c.collect(vals)
+		}
+	}
+	return vals
+}
+
+// walk calls fn with the tiles of this subtree in the zoom range, depth first in quadkey order.
+// Stops and returns false as soon as fn returns false
+func (n *trieNode) walk(t Tile, zmin, zmax int, fn func(Tile) bool) bool {
+	if t.Z > zmax {
+		return true
+	}
+	if t.Z >= zmin && !fn(t) {
+		return false
+	}
+	for q, c := range n.children {
+		if c == nil {
+			continue
+		}
+		child := Tile{X: t.X<<1 | q&1, Y: t.Y<<1 | q>>1, Z: t.Z + 1}
+		if !c.walk(child, zmin, zmax, fn) {
+			return false
+		}
+	}
+	return true
+}
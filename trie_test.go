@@ -0,0 +1,88 @@
+package tiles
+
+import (
+	"testing"
+)
+
+func TestTrieIndex(t *testing.T) {
+	idx := NewTrieIndex()
+	testIndex(t, TileIndex(idx))
+}
+
+func TestTrieTileRange(t *testing.T) {
+	qks := []string{
+		"0000",
+		"0001",
+		"0010",
+		"0011",
+		"0100",
+		"0101",
+		"1111",
+	}
+	idx := NewTrieIndex()
+	for i, qk := range qks {
+		tile, _ := FromQuadkeyString(qk)
+		idx.Add(tile, i)
+	}
+	tests := []struct {
+		zmin, zmax int
+		qks        []Quadkey
+	}{
+		{0, 0, []Quadkey{""}},
+		{1, 2, []Quadkey{"0", "00", "01", "1", "11"}},
+		{4, 9, []Quadkey{"0000", "0001", "0010", "0011", "0100", "0101", "1111"}},
+		{5, 9, []Quadkey{}},
+	}
+	errf := "TrieIndex.TileRange(%d, %d) -> %v"
+	for _, test := range tests {
+		var qks []Quadkey
+		for tile := range idx.TileRange(test.zmin, test.zmax) {
+			qks = append(qks, tile.Quadkey())
+		}
+		if !qkSliceEqual(qks, test.qks) {
+			t.Errorf(errf, test.zmin, test.zmax, qks)
+		}
+	}
+}
+
+func BenchmarkTrieValues(b *testing.B) {
+	idx := NewTrieIndex()
+	hydrateIndexN(idx, 1000000)
+	esb := Tile{X: 9649, Y: 12315, Z: 15}
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		bV = idx.Values(esb)
+	}
+}
+
+func BenchmarkKeysetValues1M(b *testing.B) {
+	idx := &KeysetIndex{}
+	hydrateIndexN(idx, 1000000)
+	idx.sort()
+	esb := Tile{X: 9649, Y: 12315, Z: 15}
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		bV = idx.Values(esb)
+	}
+}
+
+func BenchmarkTrieTileRange(b *testing.B) {
+	idx := NewTrieIndex()
+	hydrateIndexN(idx, 1000000)
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		for range idx.TileRange(10, 14) {
+		}
+	}
+}
+
+func BenchmarkKeysetTileRange1M(b *testing.B) {
+	idx := &KeysetIndex{}
+	hydrateIndexN(idx, 1000000)
+	idx.sort()
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		for range idx.TileRange(10, 14) {
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package tiles
+
+import "context"
+
+// TypedIndex is a TileIndex whose values are all of type T.
+// It uses a KeysetIndex internally, so it's thread safe and shares its aggregation semantics.
+type TypedIndex[T any] struct {
+	idx KeysetIndex
+}
+
+// NewTypedIndex returns an empty TypedIndex for values of type T
+func NewTypedIndex[T any]() *TypedIndex[T] {
+	return &TypedIndex[T]{}
+}
+
+// TileRange returns a channel of all tiles in the index in the zoom range. See KeysetIndex.TileRange
+func (idx *TypedIndex[T]) TileRange(zmin, zmax int) <-chan Tile {
+	return idx.idx.TileRange(zmin, zmax)
+}
+
+// TileRangeContext returns a channel of all tiles in the index in the zoom range. See KeysetIndex.TileRangeContext
+func (idx *TypedIndex[T]) TileRangeContext(ctx context.Context, zmin, zmax int) <-chan Tile {
+	return idx.idx.TileRangeContext(ctx, zmin, zmax)
+}
+
+// Values returns a list of values aggregated under the requested tile
+func (idx *TypedIndex[T]) Values(t Tile) []T {
+	return typed[T](idx.idx.Values(t))
+}
+
+// Add adds values to the tile
+func (idx *TypedIndex[T]) Add(t Tile, val ...T) {
+	vals := make([]interface{}, len(val))
+	for i, v := range val {
+		vals[i] = v
+	}
+	idx.idx.Add(t, vals...)
+}
+
+// Delete removes the first value stored at exactly t that is equal to val. See KeysetIndex.Delete
+func (idx *TypedIndex[T]) Delete(t Tile, val T) bool {
+	return idx.idx.Delete(t, val)
+}
+
+// Reduce folds fn over the values aggregated under the tile in idx, starting with init, without collecting them first.
+// Holds a readlock for the duration of the fold, so fn must not modify the index
+func Reduce[T, A any](idx *TypedIndex[T], t Tile, init A, fn func(A, T) A) A {
+	acc := init
+	idx.idx.ForEach(t, func(val interface{}) bool {
+		t, _ := val.(T)
+		acc = fn(acc, t)
+		return true
+	})
+	return acc
+}
+
+// converts values from the internal index, which only ever holds T or a nil for an interface T
+func typed[T any](vals []interface{}) []T {
+	if vals == nil {
+		return nil
+	}
+	ts := make([]T, len(vals))
+	for i, v := range vals {
+		// a nil stored for an interface T comes back as T's zero value
+		ts[i], _ = v.(T)
+	}
+	return ts
+}
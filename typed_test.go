@@ -0,0 +1,84 @@
+package tiles
+
+import (
+	"errors"
+	"testing"
+)
+
+type landmark struct {
+	Name   string
+	Height int
+}
+
+func TestTypedIndex(t *testing.T) {
+	idx := NewTypedIndex[landmark]()
+	esb := FromCoordinate(40.7484, -73.9857, 18)
+	sol := FromCoordinate(40.6892, -74.0445, 18)
+	bbn := FromCoordinate(51.5007, -0.1246, 18)
+	idx.Add(esb, landmark{"EmpireStateBuilding", 443})
+	idx.Add(sol, landmark{"StatueOfLiberty", 93})
+	idx.Add(bbn, landmark{"BigBen", 96})
+	nyc := Tile{X: 75, Y: 96, Z: 8}
+	den := Tile{X: 106, Y: 194, Z: 9}
+	h := 0
+	for _, l := range idx.Values(nyc) {
+		h += l.Height
+	}
+	switch {
+	case len(idx.Values(esb)) != 1 || idx.Values(esb)[0].Name != "EmpireStateBuilding":
+		t.Error("ESB: ", idx.Values(esb))
+	case h != 536:
+		t.Error("NYC: ", idx.Values(nyc))
+	case len(idx.Values(den)) != 0:
+		t.Error("DEN: ", idx.Values(den))
+	}
+	if !idx.Delete(sol, landmark{"StatueOfLiberty", 93}) || len(idx.Values(nyc)) != 1 {
+		t.Error("TypedIndex.Delete: ", idx.Values(nyc))
+	}
+	c := 0
+	for range idx.TileRange(0, 18) {
+		c++
+	}
+	if c == 0 {
+		t.Error("TypedIndex.TileRange returned no tiles")
+	}
+}
+
+func TestTypedIndexReduce(t *testing.T) {
+	idx := NewTypedIndex[landmark]()
+	idx.Add(FromCoordinate(40.7484, -73.9857, 18), landmark{"EmpireStateBuilding", 443})
+	idx.Add(FromCoordinate(40.6892, -74.0445, 18), landmark{"StatueOfLiberty", 93})
+	idx.Add(FromCoordinate(51.5007, -0.1246, 18), landmark{"BigBen", 96})
+	tallest := Reduce(idx, Tile{}, landmark{}, func(acc landmark, l landmark) landmark {
+		if l.Height > acc.Height {
+			return l
+		}
+		return acc
+	})
+	if tallest.Name != "EmpireStateBuilding" {
+		t.Error("Reduce tallest: ", tallest)
+	}
+	n := Reduce(idx, Tile{X: 75, Y: 96, Z: 8}, 0, func(acc int, l landmark) int { return acc + 1 })
+	if n != 2 {
+		t.Error("Reduce NYC count: ", n)
+	}
+}
+
+func TestTypedIndexNilInterface(t *testing.T) {
+	idx := NewTypedIndex[error]()
+	esb := FromCoordinate(40.7484, -73.9857, 18)
+	idx.Add(esb, nil, errors.New("closed"))
+	vals := idx.Values(esb)
+	if len(vals) != 2 || vals[0] != nil || vals[1] == nil {
+		t.Error("TypedIndex[error] Values with a nil: ", vals)
+	}
+	n := Reduce(idx, esb, 0, func(n int, err error) int {
+		if err == nil {
+			n++
+		}
+		return n
+	})
+	if n != 1 {
+		t.Error("Reduce over TypedIndex[error] with a nil counted ", n)
+	}
+}
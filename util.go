@@ -14,6 +14,9 @@ const (
 	EarthRadiusM float64 = 6378137
 )
 
+// half the width of the web mercator (EPSG:3857) projection in meters, the x and y extent of the map either side of the origin
+const mercatorExtent = math.Pi * EarthRadiusM
+
 // TileSize is the size in pixels of each tile. It can be tuned at the package level.
 var TileSize = 256
 
@@ -29,22 +32,56 @@ func clip(val, min, max float64) float64 {
 	return math.Min(math.Max(val, min), max)
 }
 
+// wraps lon into the [-180, 180) range
+func wrapLon(lon float64) float64 {
+	lon = math.Mod(lon+180, 360)
+	if lon < 0 {
+		lon += 360
+	}
+	return lon - 180
+}
+
+// degreesForMeters returns the latitude and longitude spans in degrees of a circle of radius meters centered at lat, treating the earth as a sphere of radius EarthRadiusM.
+// The longitude span widens away from the equator. If the circle reaches a pole it spans every longitude, so ok is false and dLon is 180
+func degreesForMeters(lat, meters float64) (dLat, dLon float64, ok bool) {
+	d := meters / EarthRadiusM
+	dLat = d * 180 / math.Pi
+	if math.Abs(lat)+dLat >= 90 {
+		return dLat, 180, false
+	}
+	dLon = math.Asin(math.Sin(d)/math.Cos(lat*math.Pi/180)) * 180 / math.Pi
+	return dLat, dLon, true
+}
+
 // Gets the size of the x, y dimensions in pixels at the given zoom level
 // x == y since the map is a square
 func mapDimensions(zoom int) int {
-	//TODO panic outside of zoom bounds
-	return TileSize << uint(zoom)
+	return grid().mapDimensions(zoom)
 }
 
 // Gets the ground resoultion (meters/pixel) of the map at the lat and zoom
-// TODO remove if unused
-/*
 func grndRes(lat float64, zoom int) float64 {
-	lat = clip(lat, MinLat, MaxLat)
-	dim := float64(mapDimensions(zoom))
-	return math.Cos(lat*math.Pi/180) * 2 * math.Pi * EarthRadiusM / dim
+	return grid().grndRes(lat, zoom)
+}
+
+// ZoomForResolution returns the lowest zoom whose ground resolution at lat is at most meters per pixel, for TileSize pixel tiles.
+// Resolution shrinks by cos(lat) away from the equator, so the same meters needs a lower zoom further north or south.
+// The zoom is clipped to [0, ZMax]
+func ZoomForResolution(lat, meters float64) int {
+	return grid().ZoomForResolution(lat, meters)
+}
+
+// ZoomForAccuracy returns the deepest zoom whose tiles at lat are at least meters wide, so a point known to within meters stays inside its tile's scale.
+// The zoom is clipped to [0, ZMax]
+func ZoomForAccuracy(lat, meters float64) int {
+	if meters <= 0 {
+		return ZMax
+	}
+	// the tile width at zoom 0 halves with each zoom
+	width := grndRes(lat, 0) * float64(TileSize)
+	z := int(math.Floor(math.Log2(width/meters) + 1e-9))
+	return int(clip(float64(z), 0, ZMax))
 }
-*/
 
 // Gets the map scale at the lat, zoom & screen DPI expressed as the denominator N of the ratio 1 : N.
 // TODO remove if unused
@@ -21,6 +21,27 @@ func TestClip(t *testing.T) {
 	}
 }
 
+func TestWrapLon(t *testing.T) {
+	wrapTests := []struct {
+		val, out float64
+	}{
+		{0, 0},
+		{-180, -180},
+		{180, -180},
+		{190, -170},
+		{-190, 170},
+		{540, -180},
+		{-73.9857, -73.9857},
+	}
+	errf := "wrapLon() %+v -> %+v"
+	for _, test := range wrapTests {
+		val := wrapLon(test.val)
+		if !floatEquals(val, test.out) {
+			t.Errorf(errf, test, val)
+		}
+	}
+}
+
 func TestMapDimensions(t *testing.T) {
 	mapDimTests := []struct {
 		zoom, out int
@@ -38,8 +59,6 @@ func TestMapDimensions(t *testing.T) {
 	}
 }
 
-/*
-//TODO assert neither of these are used and remove
 func TestGroundRes(t *testing.T) {
 	lat := 40.0
 	var zoom int = 7
@@ -49,6 +68,65 @@ func TestGroundRes(t *testing.T) {
 	}
 }
 
+func TestZoomForResolution(t *testing.T) {
+	zoomTests := []struct {
+		lat, meters float64
+		zoom        int
+	}{
+		{0, 156543.03392804097, 0},
+		{0, 156543.04, 0},
+		{0, 156543, 1},
+		{0, 1e9, 0},
+		{40, 936.86657226219847, 7},
+		{40, 936.8, 8},
+		{0, 936.8, 8},
+		{60, 936.8, 7},
+		{40, 1, 17},
+		{40, 1e-6, ZMax},
+		{40, 0, ZMax},
+	}
+	errf := "ZoomForResolution(%v, %v) -> %d"
+	for _, test := range zoomTests {
+		if zoom := ZoomForResolution(test.lat, test.meters); zoom != test.zoom {
+			t.Errorf(errf, test.lat, test.meters, zoom)
+		}
+		if zoom := ZoomForResolution(test.lat, test.meters); zoom > 0 && zoom < ZMax && grndRes(test.lat, zoom) > test.meters*(1+1e-9) {
+			t.Errorf(errf, test.lat, test.meters, zoom)
+		}
+	}
+}
+
+func TestZoomForAccuracy(t *testing.T) {
+	zoomTests := []struct {
+		lat, meters float64
+		zoom        int
+	}{
+		{0, 40075016.68557849, 0},
+		{0, 40075017, 0},
+		{0, 40075016, 0},
+		{0, 20037508.342789244, 1},
+		{0, 1e9, 0},
+		{40, 100, 18},
+		{60, 100, 17},
+		{0, 100, 18},
+		{0, 1, 23},
+		{40, 0, ZMax},
+	}
+	errf := "ZoomForAccuracy(%v, %v) -> %d"
+	for _, test := range zoomTests {
+		zoom := ZoomForAccuracy(test.lat, test.meters)
+		if zoom != test.zoom {
+			t.Errorf(errf, test.lat, test.meters, zoom)
+		}
+		// tiles at the zoom are at least as wide as the accuracy and those one zoom deeper aren't
+		if width := grndRes(test.lat, zoom) * float64(TileSize); zoom > 0 && zoom < ZMax && (width < test.meters*(1-1e-9) || width/2 >= test.meters) {
+			t.Errorf(errf, test.lat, test.meters, zoom)
+		}
+	}
+}
+
+/*
+//TODO assert this isn't used and remove
 func TestMapScale(t *testing.T) {
 	lat := 40.0
 	var zoom int = 7
@@ -0,0 +1,131 @@
+package tiles
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/gob"
+	"errors"
+	"io"
+)
+
+// write-ahead log record ops
+const (
+	walAdd byte = iota + 1
+	walDelete
+	walUpsert
+)
+
+// NewKeysetIndexWAL returns an empty KeysetIndex like NewKeysetIndex that appends a record of every Add, AddSorted, AddBatch, LoadFrom, Delete and Upsert to w
+// before applying it, so Replay can rebuild the index after a crash. Records are written under the write lock, so their order matches the index.
+// Other writes such as Merge, Filter, Compact or ReadFrom aren't logged, so follow them with a WriteTo snapshot and a fresh log.
+// Each record gob encodes its values on their own, so callers must gob.Register their concrete value types.
+// Panics if writing a record fails, before the write it records is applied
+func NewKeysetIndexWAL(capacity int, w io.Writer) *KeysetIndex {
+	idx := NewKeysetIndex(capacity)
+	idx.wal = w
+	return idx
+}
+
+// log writes a record of the write to the WAL if there is one, as the op, quadkey, id and gob encoded values each length prefixed
+// Caller must hold the write lock
+func (idx *KeysetIndex) log(op byte, t Tile, id string, vals []interface{}) {
+	if idx.wal == nil {
+		return
+	}
+	var enc bytes.Buffer
+	check(gob.NewEncoder(&enc).Encode(vals))
+	var rec bytes.Buffer
+	var n [binary.MaxVarintLen64]byte
+	field := func(b []byte) {
+		rec.Write(n[:binary.PutUvarint(n[:], uint64(len(b)))])
+		rec.Write(b)
+	}
+	rec.WriteByte(op)
+	field([]byte(t.Quadkey()))
+	field([]byte(id))
+	field(enc.Bytes())
+	// a single write keeps a record together for writers that append atomically
+	_, err := idx.wal.Write(rec.Bytes())
+	check(err)
+}
+
+// walRecord is a write read back from a WAL
+type walRecord struct {
+	op   byte
+	tile Tile
+	id   string
+	vals []interface{}
+}
+
+// Replay applies the writes recorded by NewKeysetIndexWAL in r to the index in order and returns the number applied.
+// The records are read before the index is locked, then applied under a single lock without logging them again or sending watch events.
+// A record cut short by a crash returns io.ErrUnexpectedEOF, and the records before it are still applied.
+// Values are decoded as interface{}, so callers must gob.Register their concrete value types
+func (idx *KeysetIndex) Replay(r io.Reader) (n int, err error) {
+	var recs []walRecord
+	br := bufio.NewReader(r)
+	for {
+		var rec walRecord
+		if rec, err = readRecord(br); err != nil {
+			break
+		}
+		recs = append(recs, rec)
+	}
+	if err == io.EOF {
+		err = nil
+	}
+	idx.Lock()
+	defer idx.Unlock()
+	for _, rec := range recs {
+		switch rec.op {
+		case walAdd:
+			idx.add(rec.tile, rec.vals)
+		case walDelete:
+			idx.delete(rec.tile, rec.vals[0])
+		case walUpsert:
+			idx.upsert(rec.tile, rec.id, rec.vals[0])
+		}
+	}
+	return len(recs), err
+}
+
+// readRecord reads the next record, returning io.EOF if r ends cleanly before it
+func readRecord(r *bufio.Reader) (rec walRecord, err error) {
+	if rec.op, err = r.ReadByte(); err != nil {
+		return
+	}
+	// the record has started, so running out of input now means it was cut short
+	defer func() {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+	}()
+	field := func() []byte {
+		if err != nil {
+			return nil
+		}
+		var size uint64
+		if size, err = binary.ReadUvarint(r); err != nil {
+			return nil
+		}
+		b := make([]byte, size)
+		_, err = io.ReadFull(r, b)
+		return b
+	}
+	qk, id, enc := field(), field(), field()
+	if err != nil {
+		return
+	}
+	if rec.tile, err = FromQuadkeyString(string(qk)); err != nil {
+		return
+	}
+	rec.id = string(id)
+	if err = gob.NewDecoder(bytes.NewReader(enc)).Decode(&rec.vals); err != nil {
+		return
+	}
+	if rec.op < walAdd || rec.op > walUpsert || (rec.op != walAdd && len(rec.vals) != 1) {
+		err = errors.New("Invalid WAL record")
+	}
+	return
+}
@@ -0,0 +1,112 @@
+package tiles
+
+import (
+	"bytes"
+	"context"
+	"encoding/gob"
+	"errors"
+	"io"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestKeysetIndexReplay(t *testing.T) {
+	gob.Register(landmark{})
+	var wal bytes.Buffer
+	idx := NewKeysetIndexWAL(4, &wal)
+	esb := FromCoordinate(40.7484, -73.9857, 18)
+	sol := FromCoordinate(40.6892, -74.0445, 18)
+	bbn := FromCoordinate(51.5007, -0.1246, 18)
+	idx.Add(esb, landmark{"EmpireStateBuilding", 443}, "ChryslerBuilding")
+	idx.AddSorted(sol, "StatueOfLiberty")
+	idx.Upsert(bbn, "plane", "Plane@BBN")
+	idx.Upsert(sol, "plane", "Plane@SOL")
+	idx.Delete(esb, "ChryslerBuilding")
+	idx.Delete(esb, "Macy's")
+	idx.AddBatch([]Entry{{bbn, "BigBen"}, {Tile{}, nil}})
+	idx.Add(sol)
+	records := 9
+	log := wal.Bytes()
+	cp := &KeysetIndex{}
+	if n, err := cp.Replay(bytes.NewReader(log)); n != records || err != nil {
+		t.Fatalf("KeysetIndex.Replay() -> %d, %v", n, err)
+	}
+	for _, tile := range []Tile{esb, sol, bbn, {X: 75, Y: 96, Z: 8}, {}} {
+		if !reflect.DeepEqual(idx.Values(tile), cp.Values(tile)) {
+			t.Errorf("Replay Values(%+v) %v -> %v", tile, idx.Values(tile), cp.Values(tile))
+		}
+	}
+	if v, ok := cp.Get(sol, "plane"); !ok || v != "Plane@SOL" {
+		t.Error("Replay lost the upserted id: ", v, ok)
+	}
+	if wal.Len() != len(log) {
+		t.Error("Replay into an index without a WAL wrote to the WAL")
+	}
+	// a record torn by a crash stops the replay, keeping the records before it
+	torn := &KeysetIndex{}
+	if n, err := torn.Replay(bytes.NewReader(log[:len(log)-3])); n != records-1 || err != io.ErrUnexpectedEOF {
+		t.Errorf("KeysetIndex.Replay(torn) -> %d, %v", n, err)
+	}
+	if v := torn.Values(Tile{}); len(v) != len(idx.Values(Tile{})) {
+		t.Error("Replay(torn) values: ", v)
+	}
+	if n, err := torn.Replay(bytes.NewBufferString("\x07\x00\x00\x00")); n != 0 || err == nil {
+		t.Errorf("KeysetIndex.Replay(invalid) -> %d, %v", n, err)
+	}
+}
+
+type failWriter struct{}
+
+func (failWriter) Write(p []byte) (int, error) {
+	return 0, errors.New("disk full")
+}
+
+func TestKeysetIndexWALFailure(t *testing.T) {
+	idx := NewKeysetIndexWAL(1, failWriter{})
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Error("Add with a failing WAL didn't panic")
+			}
+		}()
+		idx.Add(Tile{}, "World")
+	}()
+	if idx.Len() != 0 {
+		t.Error("Add with a failing WAL was applied")
+	}
+}
+
+func TestKeysetIndexWALFailureLoadFrom(t *testing.T) {
+	idx := NewKeysetIndexWAL(1, failWriter{})
+	ch := make(chan Entry, 1)
+	ch <- Entry{Tile{}, "World"}
+	close(ch)
+	done := make(chan interface{})
+	go func() {
+		defer func() { done <- recover() }()
+		idx.LoadFrom(context.Background(), ch)
+	}()
+	select {
+	case r := <-done:
+		if r == nil {
+			t.Error("LoadFrom with a failing WAL didn't panic")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("LoadFrom with a failing WAL hung")
+	}
+	locked := make(chan struct{})
+	go func() {
+		idx.Lock()
+		idx.Unlock()
+		close(locked)
+	}()
+	select {
+	case <-locked:
+	case <-time.After(time.Second):
+		t.Error("LoadFrom with a failing WAL left the index locked")
+	}
+	if idx.Len() != 0 {
+		t.Error("LoadFrom with a failing WAL applied ", idx.Len())
+	}
+}
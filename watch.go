@@ -0,0 +1,73 @@
+package tiles
+
+// EventKind is the kind of mutation an Event reports
+type EventKind int
+
+const (
+	// EventAdd is sent for each value added by Add or AddSorted
+	EventAdd EventKind = iota
+	// EventDelete is sent for a value removed by Delete or replaced by Upsert
+	EventDelete
+	// EventUpsert is sent for the value stored by Upsert
+	EventUpsert
+)
+
+func (k EventKind) String() string {
+	switch k {
+	case EventAdd:
+		return "add"
+	case EventDelete:
+		return "delete"
+	case EventUpsert:
+		return "upsert"
+	}
+	return "unknown"
+}
+
+// Event is a mutation of a KeysetIndex sent to the channels registered with Watch
+type Event struct {
+	Kind  EventKind
+	Tile  Tile
+	Value interface{}
+}
+
+// Watch registers ch to receive an Event for each value added by Add, AddSorted or Upsert and each removed by Delete or replaced by Upsert.
+// Events are sent after the write lock is released and without blocking, so they're dropped if ch is full; give it a buffer sized for bursts.
+// Bulk operations like AddBatch, Merge, Filter and Reset don't send events. Registering a channel twice sends each event to it twice
+func (idx *KeysetIndex) Watch(ch chan<- Event) {
+	idx.watchMu.Lock()
+	defer idx.watchMu.Unlock()
+	idx.watchers = append(idx.watchers, ch)
+}
+
+// Unwatch deregisters ch so it no longer receives events. Once it returns nothing more is sent to ch, so it's safe to close it
+func (idx *KeysetIndex) Unwatch(ch chan<- Event) {
+	idx.watchMu.Lock()
+	defer idx.watchMu.Unlock()
+	watchers := idx.watchers[:0]
+	for _, w := range idx.watchers {
+		if w != ch {
+			watchers = append(watchers, w)
+		}
+	}
+	// clear the tail so dropped channels aren't kept reachable
+	for i := len(watchers); i < len(idx.watchers); i++ {
+		idx.watchers[i] = nil
+	}
+	idx.watchers = watchers
+}
+
+// emit sends an event for each value to the watchers, dropping it for those that are full
+// Caller must not hold the index lock
+func (idx *KeysetIndex) emit(kind EventKind, t Tile, vals ...interface{}) {
+	idx.watchMu.Lock()
+	defer idx.watchMu.Unlock()
+	for _, w := range idx.watchers {
+		for _, v := range vals {
+			select {
+			case w <- Event{Kind: kind, Tile: t, Value: v}:
+			default:
+			}
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package tiles
+
+import (
+	"reflect"
+	"sync"
+	"testing"
+)
+
+func TestKeysetIndexWatch(t *testing.T) {
+	idx := &KeysetIndex{}
+	esb := FromCoordinate(40.7484, -73.9857, 18)
+	sol := FromCoordinate(40.6892, -74.0445, 18)
+	ch := make(chan Event, 16)
+	idx.Watch(ch)
+	idx.Add(esb, "EmpireStateBuilding", "ChryslerBuilding")
+	idx.AddSorted(sol, "StatueOfLiberty")
+	idx.Delete(esb, "ChryslerBuilding")
+	idx.Delete(esb, "Missing")
+	idx.Upsert(esb, "ferry", "Ferry")
+	idx.Upsert(sol, "ferry", "Ferry")
+	idx.AddBatch([]Entry{{esb, "Batched"}})
+	exp := []Event{
+		{EventAdd, esb, "EmpireStateBuilding"},
+		{EventAdd, esb, "ChryslerBuilding"},
+		{EventAdd, sol, "StatueOfLiberty"},
+		{EventDelete, esb, "ChryslerBuilding"},
+		{EventUpsert, esb, "Ferry"},
+		{EventDelete, esb, "Ferry"},
+		{EventUpsert, sol, "Ferry"},
+	}
+	var events []Event
+	for len(ch) > 0 {
+		events = append(events, <-ch)
+	}
+	if !reflect.DeepEqual(events, exp) {
+		t.Errorf("Watch events -> %v", events)
+	}
+	idx.Unwatch(ch)
+	idx.Add(esb, "Unwatched")
+	if len(ch) != 0 {
+		t.Errorf("Unwatch still sent %v", <-ch)
+	}
+}
+
+func TestKeysetIndexWatchFull(t *testing.T) {
+	idx := &KeysetIndex{}
+	full, other := make(chan Event), make(chan Event, 4)
+	idx.Watch(full)
+	idx.Watch(other)
+	done := make(chan struct{})
+	go func() {
+		idx.Add(Tile{X: 1, Y: 1, Z: 1}, 1, 2)
+		close(done)
+	}()
+	<-done
+	if len(other) != 2 {
+		t.Errorf("Watch with a full channel sent %d events to the other", len(other))
+	}
+	// a watcher can read the index while handling events since they're sent without the index's lock
+	var wg sync.WaitGroup
+	ch := make(chan Event, 1)
+	idx.Watch(ch)
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		e := <-ch
+		if n := idx.Count(e.Tile); n != 3 {
+			t.Errorf("Count after %v -> %d", e, n)
+		}
+	}()
+	idx.Add(Tile{X: 1, Y: 1, Z: 1}, 3)
+	wg.Wait()
+}